@@ -2,8 +2,10 @@ package backendaction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -32,14 +34,36 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		o.Content = string(stdinContent)
 	}
 
-	body := ""
+	var bodyFileContent []byte
 	if o.BodyFile != nil {
-		bodyFileContent, err := io.ReadAll(o.BodyFile)
+		bodyFileContent, err = io.ReadAll(o.BodyFile)
 		if err != nil {
 			return fmt.Errorf("reading body file: %w", err)
 		}
+	}
+
+	if o.Arrival.Enabled() {
+		// Every arrival sends its own request. The body is built new each
+		// time, so every request gets new uuids.
+		return o.Arrival.Run(ctx, 0, func(ctx context.Context, i int) {
+			if err := sendAction(ctx, c, o.body(bodyFileContent)); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("Request %d: %v", i+1, err)
+			}
+		})
+	}
 
-		body = fmt.Sprintf(
+	if err := sendAction(ctx, c, o.body(bodyFileContent)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// body returns the request body. If bodyFileContent is set, it is used as
+// the data of the action.
+func (o Options) body(bodyFileContent []byte) string {
+	if bodyFileContent != nil {
+		return fmt.Sprintf(
 			`[{
 				"action": "%s",
 				"data": %s
@@ -47,25 +71,27 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 			o.Action,
 			bodyFileContent,
 		)
-	} else {
-		actions := make([]string, o.Amount)
-		for i := 0; i < len(actions); i++ {
-			c := o.Content
-			c = strings.ReplaceAll(c, `\i`, strconv.Itoa(i+1))
-			c = strings.ReplaceAll(c, `\u`, uuid.New().String())
-
-			actions[i] = c
-		}
+	}
 
-		body = fmt.Sprintf(
-			`[{
-				"action": "%s",
-				"data": [%s]
-			}]`,
-			o.Action,
-			strings.Join(actions, ","),
-		)
+	actions := make([]string, o.Amount)
+	for i := 0; i < len(actions); i++ {
+		c := o.Content
+		c = strings.ReplaceAll(c, `\i`, strconv.Itoa(i+1))
+		c = strings.ReplaceAll(c, `\u`, uuid.New().String())
+		actions[i] = c
 	}
+
+	return fmt.Sprintf(
+		`[{
+			"action": "%s",
+			"data": [%s]
+		}]`,
+		o.Action,
+		strings.Join(actions, ","),
+	)
+}
+
+func sendAction(ctx context.Context, c *client.Client, body string) error {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
@@ -75,11 +101,14 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
+
 	req.Header.Set("Content-Type", "application/json")
 
-	if _, err := c.Do(req); err != nil {
+	resp, err := c.Do(req)
+	if err != nil {
 		return fmt.Errorf("sending request: %w", err)
 	}
+	resp.Body.Close()
 
 	return nil
 }
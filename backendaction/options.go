@@ -1,6 +1,10 @@
 package backendaction
 
-import "os"
+import (
+	"os"
+
+	"github.com/OpenSlides/openslides-performance/rate"
+)
 
 // Options is the meta information for the cli.
 type Options struct {
@@ -8,6 +12,8 @@ type Options struct {
 	Amount   int    `help:"Amount of action to be called." short:"n" default:"10"`
 	Content  string `help:"content of the action." short:"c" default:""`
 	BodyFile *os.File `help:"File containing the 'data' array of action payload sent to the backend" short:"b"`
+
+	Arrival rate.Options `embed:""`
 }
 
 // Help returns the help message
@@ -20,6 +26,9 @@ In the action content \i is replaced with a number between 1 and amount.
 
 If content is "-", then the content is read from stdin.
 
+With --rate, the request is sent again and again with the given arrival rate
+until the command is stopped.
+
 Example:
 
 openslides-performance backend-action motion.create -c '{"meeting_id":1,"text":"hello world","title":"motion\u"}'`
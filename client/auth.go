@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// metricTokenRefresh is the name that is used to record the refreshes of the
+// auth token.
+const metricTokenRefresh = "token refresh"
+
+// credentials returns the current auth token and the refresh cookie.
+func (c *Client) credentials() (string, *http.Cookie) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	return c.authToken, c.authCookie
+}
+
+// setCredentials sets the auth token and the refresh cookie.
+func (c *Client) setCredentials(authToken string, authCookie *http.Cookie) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.authToken = authToken
+	c.authCookie = authCookie
+}
+
+// updateAuthToken uses a new auth token from the response.
+//
+// The auth service can send a new token with any response.
+func (c *Client) updateAuthToken(resp *http.Response) {
+	token := resp.Header.Get("authentication")
+	if token == "" {
+		return
+	}
+
+	c.authMu.Lock()
+	// Only update the token of a logged in client. Otherwise the login
+	// response would be used before the cookie is set.
+	loggedIn := c.authCookie != nil
+	if loggedIn {
+		c.authToken = token
+	}
+	c.authMu.Unlock()
+
+	if loggedIn {
+		c.cfg.Sessions.updateToken(c.username, token)
+	}
+}
+
+// refresh gets a new auth token from the auth service by using the refresh
+// cookie.
+//
+// oldToken is the token that was rejected. If the token was changed in the
+// meantime, for example by a parallel request, no new refresh is done.
+func (c *Client) refresh(ctx context.Context, oldToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	authToken, authCookie := c.credentials()
+	if authToken != oldToken {
+		return nil
+	}
+
+	if authCookie == nil {
+		return fmt.Errorf("client is not logged in")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.Addr()+"/system/auth/who-am-i/", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("authentication", authToken)
+	req.Header.Add("cookie", authCookie.String())
+
+	start := time.Now()
+	resp, err := checkStatus(c.httpClient.Do(req))
+	c.record(metricTokenRefresh, start, err)
+	if err != nil {
+		return fmt.Errorf("sending refresh request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.ReadAll(resp.Body)
+
+	newToken := resp.Header.Get("authentication")
+	if newToken == "" {
+		return fmt.Errorf("response has no auth token")
+	}
+
+	c.setCredentials(newToken, authCookie)
+	c.cfg.Sessions.updateToken(c.username, newToken)
+	return nil
+}
+
+// tokenRejected returns true, if the error means, that the auth token is
+// invalid or expired.
+//
+// This is the case for the status 401. A 403 usually means, that the user has
+// not the permission for the request. It only means a rejected token, if the
+// body says, that the token expired.
+func tokenRejected(err HTTPStatusError) bool {
+	switch err.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		return bytes.Contains(bytes.ToLower(err.Body), []byte("expired"))
+	default:
+		return false
+	}
+}
+
+// canResend returns true, if the request can be sent a second time.
+func canResend(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
@@ -15,6 +15,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/quic-go/quic-go/http3"
 	"nhooyr.io/websocket"
@@ -25,9 +27,13 @@ type Client struct {
 	cfg        Config
 	httpClient *http.Client
 
+	authMu     sync.Mutex // Protects authCookie and authToken.
 	authCookie *http.Cookie
 	authToken  string
 	userID     int
+	username   string
+
+	refreshMu sync.Mutex // Makes sure, that only one refresh is running.
 }
 
 // New initializes a new client.
@@ -83,9 +89,61 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 }
 
 // DoRaw is like Do but without backand worker.
+//
+// If the auth token was rejected, it is refreshed and the request is sent
+// again. See tokenRejected. In this case, only the second request is recorded,
+// so a token refresh is not counted as an error of the request.
 func (c *Client) DoRaw(req *http.Request) (*http.Response, error) {
-	req.Header.Set("authentication", c.authToken)
-	req.Header.Add("cookie", c.authCookie.String())
+	authToken, authCookie, err := c.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.roundTrip(req)
+
+	var errStatus HTTPStatusError
+	if !errors.As(err, &errStatus) || !tokenRejected(errStatus) || authCookie == nil || !canResend(req) {
+		c.record(req.URL.Path, start, err)
+		return resp, err
+	}
+
+	if refreshErr := c.refresh(req.Context(), authToken); refreshErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("refreshing auth token: %w", refreshErr))
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("resetting body: %w", err)
+		}
+		req.Body = body
+	}
+
+	newToken, _ := c.credentials()
+	req.Header.Set("authentication", newToken)
+	return c.send(req)
+}
+
+// DoOnce is like DoRaw, but the request is sent exactly once, also if the auth
+// token was rejected. The latency is not recorded.
+//
+// It is meant for requests, that are expected to fail. The caller has to
+// record them.
+func (c *Client) DoOnce(req *http.Request) (*http.Response, error) {
+	if _, _, err := c.prepare(req); err != nil {
+		return nil, err
+	}
+
+	return c.roundTrip(req)
+}
+
+// prepare sets the credentials, the content type and the address of the
+// server. It returns the credentials, that were used.
+func (c *Client) prepare(req *http.Request) (string, *http.Cookie, error) {
+	authToken, authCookie := c.credentials()
+	req.Header.Set("authentication", authToken)
+	req.Header.Add("cookie", authCookie.String())
 
 	if req.Header.Get("content-type") == "" {
 		req.Header.Set("content-type", "application/json")
@@ -94,24 +152,49 @@ func (c *Client) DoRaw(req *http.Request) (*http.Response, error) {
 	if req.URL.Host == "" {
 		base, err := url.Parse(c.cfg.Addr())
 		if err != nil {
-			return nil, fmt.Errorf("parsing base url: %w", err)
+			return "", nil, fmt.Errorf("parsing base url: %w", err)
 		}
 
 		req.URL = base.ResolveReference(req.URL)
 	}
 
-	return checkStatus(c.httpClient.Do(req))
+	return authToken, authCookie, nil
+}
+
+// send sends the request and records the latency.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.roundTrip(req)
+	c.record(req.URL.Path, start, err)
+	return resp, err
+}
+
+// roundTrip sends the request, counts it and updates the auth token.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	c.cfg.Stats.AddGauge("requests_in_flight", 1)
+	defer c.cfg.Stats.AddGauge("requests_in_flight", -1)
+
+	resp, err := c.httpClient.Do(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		c.updateAuthToken(resp)
+	}
+	c.cfg.Stats.Inc("requests_total", "path", req.URL.Path, "status", status)
+
+	return checkStatus(resp, err)
 }
 
 // DoTask is like Do, but returns a Task.
 func (c *Client) DoTask(req *http.Request) (*Task, error) {
+	start := time.Now()
 	resp, err := c.DoRaw(req)
 	if err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
 	}
 
 	if resp.StatusCode == 202 {
-		return c.backendWorker(req.Context(), resp)
+		return c.backendWorker(req.Context(), resp, start)
 	}
 
 	closedCh := make(chan struct{})
@@ -138,9 +221,10 @@ func (c *Client) Dial(ctx context.Context, rawURL string) (*websocket.Conn, *htt
 		url = base.ResolveReference(url)
 	}
 
+	authToken, authCookie := c.credentials()
 	header := http.Header{}
-	header.Set("authentication", c.authToken)
-	header.Add("cookie", c.authCookie.String())
+	header.Set("authentication", authToken)
+	header.Add("cookie", authCookie.String())
 
 	return websocket.Dial(ctx, url.String(), &websocket.DialOptions{
 		HTTPClient: c.httpClient,
@@ -148,7 +232,9 @@ func (c *Client) Dial(ctx context.Context, rawURL string) (*websocket.Conn, *htt
 	})
 }
 
-func (c *Client) backendWorker(ctx context.Context, resp *http.Response) (*Task, error) {
+// backendWorker returns a task that is done, when the action worker has
+// finished. start is the time when the action was sent.
+func (c *Client) backendWorker(ctx context.Context, resp *http.Response, start time.Time) (*Task, error) {
 	awID, err := actionWorkerID(resp)
 	if err != nil {
 		return nil, fmt.Errorf("unpacking action worker id: %w", err)
@@ -163,8 +249,12 @@ func (c *Client) backendWorker(ctx context.Context, resp *http.Response) (*Task,
 		done: make(chan struct{}),
 	}
 
+	c.cfg.Stats.AddGauge("action_workers_pending", 1)
 	go func() {
-		task.setDone(parseAutoupdate(awID, autoUpdateResp))
+		resp, err := parseAutoupdate(awID, autoUpdateResp)
+		c.cfg.Stats.AddGauge("action_workers_pending", -1)
+		c.record(metricActionWorker, start, err)
+		task.setDone(resp, err)
 		autoUpdateResp.Body.Close()
 	}()
 
@@ -280,6 +370,13 @@ func (c *Client) LoginWithCredentials(ctx context.Context, username, password st
 		return nil
 	}
 
+	if se, ok := c.cfg.Sessions.get(username); ok {
+		c.setCredentials(se.authToken, se.authCookie)
+		c.userID = se.userID
+		c.username = username
+		return nil
+	}
+
 	url := c.cfg.Addr() + "/system/auth/login"
 	payload := fmt.Sprintf(`{"username": "%s", "password": "%s"}`, username, password)
 
@@ -289,6 +386,7 @@ func (c *Client) LoginWithCredentials(ctx context.Context, username, password st
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	var resp *http.Response
 	for retry := 0; retry < 100; retry++ {
 		resp, err = checkStatus(c.httpClient.Do(req))
@@ -305,26 +403,38 @@ func (c *Client) LoginWithCredentials(ctx context.Context, username, password st
 			return ctx.Err()
 		}
 	}
+
+	c.record(metricLogin, start, err)
 	if err != nil {
+		c.cfg.Stats.Inc("logins_total", "result", "failed")
 		return fmt.Errorf("sending login request: %w", err)
 	}
+	c.cfg.Stats.Inc("logins_total", "result", "success")
 	defer resp.Body.Close()
 	io.ReadAll(resp.Body)
 
-	c.authToken = resp.Header.Get("authentication")
+	authToken := resp.Header.Get("authentication")
+	var authCookie *http.Cookie
 	for _, cookie := range resp.Cookies() {
 		if cookie.Name == "refreshId" {
-			c.authCookie = cookie
+			authCookie = cookie
 			break
 		}
 	}
+	c.setCredentials(authToken, authCookie)
 
-	id, err := decodeUserID(c.authToken)
+	id, err := decodeUserID(authToken)
 	if err != nil {
 		return fmt.Errorf("decoding user id from auth token: %w", err)
 	}
 
 	c.userID = id
+	c.username = username
+	c.cfg.Sessions.set(username, session{
+		authToken:  authToken,
+		authCookie: authCookie,
+		userID:     id,
+	})
 	return nil
 }
 
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/stats"
 )
 
 func TestLogin(t *testing.T) {
@@ -115,3 +116,126 @@ func TestBackendActionWorkerAbborted(t *testing.T) {
 		t.Errorf("got error `%v`, expected `%v`", err, err)
 	}
 }
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	fakeServer := newServerSub()
+	ts := httptest.NewServer(fakeServer)
+	collector := stats.New()
+	c, err := client.New(client.Config{
+		Domain: ts.URL,
+		Stats:  collector,
+	})
+	if err != nil {
+		t.Fatalf("client.New(): %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/system/action/handle_request", strings.NewReader("fake-body"))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	fakeServer.backendReturnStatus = 400
+	if _, err := c.Do(req); err == nil {
+		t.Fatalf("sending request did not return an error")
+	}
+
+	h := collector.Histogram("/system/action/handle_request")
+	if h.Count() != 1 {
+		t.Errorf("got %d recorded requests, expected 1", h.Count())
+	}
+}
+
+func TestTokenRefreshOnlyForRejectedTokens(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		status        int
+		body          string
+		expectSent    int
+		expectRefresh int
+	}{
+		{"permission denied", 403, `{"success":false,"message":"You are not allowed to perform action topic.create."}`, 1, 0},
+		{"expired token", 403, `{"success":false,"message":"Token expired"}`, 2, 1},
+		{"unauthorized", 401, `{"success":false,"message":"Invalid token"}`, 2, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fakeServer := newServerSub()
+			ts := httptest.NewServer(fakeServer)
+			defer ts.Close()
+
+			c, err := client.New(client.Config{
+				Domain: ts.URL,
+			})
+			if err != nil {
+				t.Fatalf("client.New(): %v", err)
+			}
+
+			if err := c.Login(ctx); err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, "POST", "/system/action/handle_request", strings.NewReader("fake-body"))
+			if err != nil {
+				t.Fatalf("creating request: %v", err)
+			}
+
+			fakeServer.backendReturnStatus = tt.status
+			fakeServer.backendReturnBody = tt.body
+			if _, err := c.Do(req); err == nil {
+				t.Fatalf("sending request did not return an error")
+			}
+
+			if fakeServer.backendRequests != tt.expectSent {
+				t.Errorf("request was sent %d times, expected %d", fakeServer.backendRequests, tt.expectSent)
+			}
+
+			if fakeServer.refreshRequests != tt.expectRefresh {
+				t.Errorf("got %d refreshes, expected %d", fakeServer.refreshRequests, tt.expectRefresh)
+			}
+		})
+	}
+}
+
+func TestTokenRefreshRecordsOnlyTheRetry(t *testing.T) {
+	ctx := context.Background()
+	fakeServer := newServerSub()
+	fakeServer.backendFirstStatus = 401
+	ts := httptest.NewServer(fakeServer)
+	defer ts.Close()
+
+	collector := stats.New()
+	c, err := client.New(client.Config{
+		Domain: ts.URL,
+		Stats:  collector,
+	})
+	if err != nil {
+		t.Fatalf("client.New(): %v", err)
+	}
+
+	if err := c.Login(ctx); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/system/action/handle_request", strings.NewReader("fake-body"))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	if _, err := c.Do(req); err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+
+	if fakeServer.backendRequests != 2 {
+		t.Errorf("request was sent %d times, expected 2", fakeServer.backendRequests)
+	}
+
+	result, ok := stats.Result{Metrics: collector.Metrics()}.Metric("/system/action/handle_request")
+	if !ok {
+		t.Fatalf("request was not recorded")
+	}
+
+	if result.Count != 1 || result.ErrorCount() != 0 {
+		t.Errorf("recorded %d requests with %d errors, expected 1 request without errors", result.Count, result.ErrorCount())
+	}
+}
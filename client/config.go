@@ -3,6 +3,8 @@ package client
 import (
 	"strings"
 	"time"
+
+	"github.com/OpenSlides/openslides-performance/stats"
 )
 
 // Config for all commands.
@@ -14,8 +16,25 @@ type Config struct {
 	IPv4     bool   `help:"Force IPv4 for requests." short:"4"`
 	HTTP3    bool   `help:"Force HTTP3" short:"3"`
 	FakeAuth bool   `help:"Do not login but expect user id 1."`
+	Report   string `help:"Write the result of the command to this file. Supported file extensions are .json and .csv."`
+
+	MetricsAddr string `help:"Address for a http server that exposes prometheus metrics on /metrics while the command is running, e.g. :9100."`
+
+	Duration time.Duration `help:"Stop the command after this time. Default is to run until the command is done or stopped."`
+	RampUp   time.Duration `help:"Start the connections or workers of the command gradually in this time."`
+	RampDown time.Duration `help:"Stop the connections or workers gradually in this time before the end of --duration."`
+
+	// End is the time, when the command is stopped. It is zero, if the
+	// command has no duration.
+	End time.Time `kong:"-"`
 
 	RetryEventProvider func() <-chan struct{} `kong:"-"`
+
+	// Stats collects the latency of all requests. Can be nil.
+	Stats *stats.Collector `kong:"-"`
+
+	// Sessions shares logins between clients. Can be nil.
+	Sessions *Sessions `kong:"-"`
 }
 
 // Addr returns the domain with the http or https prefix.
@@ -12,7 +12,11 @@ type serverStub struct {
 
 	backendReturnStatus int
 	backendReturnBody   string
+	backendFirstStatus  int
 	autoupdateMessages  <-chan string
+
+	backendRequests int
+	refreshRequests int
 }
 
 func newServerSub() *serverStub {
@@ -26,6 +30,7 @@ func newServerSub() *serverStub {
 	}
 
 	s.mux.Handle("/system/auth/login", http.HandlerFunc(s.handleAuth))
+	s.mux.Handle("/system/auth/who-am-i/", http.HandlerFunc(s.handleWhoAmI))
 	s.mux.Handle("/system/action/handle_request", http.HandlerFunc(s.handleBackendAction))
 	s.mux.Handle("/system/autoupdate", http.HandlerFunc(s.handleAutoupdate))
 
@@ -51,7 +56,18 @@ func (s *serverStub) handleAuth(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &cookie)
 }
 
+func (s *serverStub) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	s.refreshRequests++
+	w.Header().Add("authentication", s.authToken)
+}
+
 func (s *serverStub) handleBackendAction(w http.ResponseWriter, r *http.Request) {
+	s.backendRequests++
+	if s.backendRequests == 1 && s.backendFirstStatus != 0 {
+		w.WriteHeader(s.backendFirstStatus)
+		return
+	}
+
 	w.WriteHeader(s.backendReturnStatus)
 	w.Write([]byte(s.backendReturnBody))
 }
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// WithDuration returns a context that is canceled after the duration of the
+// config.
+//
+// It sets c.End, so commands can stop their workers gradually before the end.
+// If no duration is set, the context is only canceled with the parent
+// context.
+func (c *Config) WithDuration(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if c.Duration <= 0 {
+		return ctx, cancel
+	}
+
+	// The context is canceled and not closed with a deadline, so the commands
+	// see context.Canceled like when the user stops the command.
+	c.End = time.Now().Add(c.Duration)
+	timer := time.AfterFunc(c.Duration, cancel)
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// Ramp starts and stops workers gradually.
+type Ramp struct {
+	start time.Time
+	end   time.Time
+	up    time.Duration
+	down  time.Duration
+	n     int
+}
+
+// Ramp returns a Ramp for n workers that begins now.
+func (c *Config) Ramp(n int) Ramp {
+	return Ramp{
+		start: time.Now(),
+		end:   c.End,
+		up:    c.RampUp,
+		down:  c.RampDown,
+		n:     n,
+	}
+}
+
+// Wait blocks until the i-th worker has to start.
+//
+// The workers are started evenly spread over the ramp up time. The returned
+// context is canceled, when the worker has to stop. The worker that started
+// first, stops first.
+func (r Ramp) Wait(ctx context.Context, i int) (context.Context, context.CancelFunc, error) {
+	if r.up > 0 && r.n > 0 {
+		timer := time.NewTimer(time.Until(r.start.Add(r.up * time.Duration(i) / time.Duration(r.n))))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if r.down <= 0 || r.end.IsZero() || r.n <= 0 {
+		return ctx, cancel, nil
+	}
+
+	stop := r.end.Add(-r.down + r.down*time.Duration(i+1)/time.Duration(r.n))
+	timer := time.AfterFunc(time.Until(stop), cancel)
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Sessions shares the login of users between many clients.
+//
+// If the config contains Sessions, a client only sends a login request, if no
+// other client has logged in with the same username before. A nil Sessions is
+// valid and does not share anything.
+type Sessions struct {
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+type session struct {
+	authToken  string
+	authCookie *http.Cookie
+	userID     int
+}
+
+// NewSessions initializes an empty Sessions.
+func NewSessions() *Sessions {
+	return &Sessions{
+		sessions: make(map[string]session),
+	}
+}
+
+func (s *Sessions) get(username string) (session, bool) {
+	if s == nil {
+		return session{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	se, ok := s.sessions[username]
+	return se, ok
+}
+
+func (s *Sessions) set(username string, se session) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[username] = se
+}
+
+// updateToken replaces the auth token of an existing session, so clients that
+// log in later get the refreshed token.
+func (s *Sessions) updateToken(username, authToken string) {
+	if s == nil || username == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	se, ok := s.sessions[username]
+	if !ok {
+		return
+	}
+	se.authToken = authToken
+	s.sessions[username] = se
+}
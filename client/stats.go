@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// metricActionWorker is the name that is used to record the time until a
+// backend action worker is done.
+const metricActionWorker = "action worker"
+
+// metricLogin is the name that is used to record the login requests.
+const metricLogin = "login"
+
+// record saves the duration since start and the error in the stats collector
+// of the config.
+//
+// Requests that are canceled by the context are not recorded. They are
+// usually the result of stopping the command.
+func (c *Client) record(name string, start time.Time, err error) {
+	stats := c.cfg.Stats
+	if stats == nil {
+		return
+	}
+
+	var errStatus HTTPStatusError
+	switch {
+	case err == nil:
+		stats.Add(name, time.Since(start))
+
+	case errors.As(err, &errStatus):
+		stats.Add(name, time.Since(start))
+		stats.AddError(name, fmt.Sprintf("status %d", errStatus.StatusCode))
+
+	case errors.Is(err, ErrAbborted):
+		stats.Add(name, time.Since(start))
+		stats.AddError(name, "aborted")
+
+	case errors.Is(err, context.Canceled):
+
+	default:
+		stats.AddError(name, "transport")
+	}
+}
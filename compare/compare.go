@@ -0,0 +1,187 @@
+package compare
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+// Run runs the command.
+func (o Options) Run(ctx context.Context) error {
+	baseline, err := stats.ReadResultFile(o.Baseline)
+	if err != nil {
+		return fmt.Errorf("reading baseline: %w", err)
+	}
+
+	candidate, err := stats.ReadResultFile(o.Candidate)
+	if err != nil {
+		return fmt.Errorf("reading candidate: %w", err)
+	}
+
+	failures, err := o.compare(os.Stdout, baseline, candidate)
+	if err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("comparison failed:\n%s", strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+// compare writes the table of all values to w and returns a message for each
+// check that failed.
+func (o Options) compare(w io.Writer, baseline, candidate stats.Result) ([]string, error) {
+	if baseline.Command != candidate.Command {
+		fmt.Fprintf(w, "Warning: comparing command %q with %q\n\n", baseline.Command, candidate.Command)
+	}
+
+	thresholds, err := o.thresholds()
+	if err != nil {
+		return nil, err
+	}
+
+	var failures, warnings []string
+	if candidate.Error != "" {
+		failures = append(failures, fmt.Sprintf("candidate run failed: %s", candidate.Error))
+	}
+
+	names := o.Name
+	if len(names) == 0 {
+		names = metricNames(baseline, candidate)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tvalue\tbaseline\tcandidate\tdelta\t")
+	for _, name := range names {
+		base, inBase := baseline.Metric(name)
+		cand, inCand := candidate.Metric(name)
+		switch {
+		case !inBase && !inCand:
+			failures = append(failures, fmt.Sprintf("%s is in no result", name))
+			fmt.Fprintf(tw, "%s\t\t-\t-\t\tFAIL\n", name)
+			continue
+
+		case !inCand:
+			failures = append(failures, fmt.Sprintf("%s is missing in the candidate", name))
+			fmt.Fprintf(tw, "%s\tcount\t%d\t-\tmissing\tFAIL\n", name, base.Count)
+			continue
+
+		case !inBase:
+			// Some metrics like the token refresh only appear, if something
+			// happened during the run. So this is no failure.
+			warnings = append(warnings, fmt.Sprintf("Warning: %s is only in the candidate", name))
+			fmt.Fprintf(tw, "%s\tcount\t-\t%d\tnew\t\n", name, cand.Count)
+			continue
+		}
+
+		for _, v := range values {
+			b := v.get(base)
+			c := v.get(cand)
+			d, ok := delta(b, c)
+
+			deltaText := fmt.Sprintf("%+.1f%%", d)
+			if !ok {
+				deltaText = "new"
+			}
+
+			mark := ""
+			maxIncrease, checked := thresholds[v.name]
+			switch {
+			case checked && !ok:
+				mark = "FAIL"
+				failures = append(failures, fmt.Sprintf("%s %s is %.2f but 0 in the baseline", name, v.name, c))
+
+			case checked && d > maxIncrease:
+				mark = "FAIL"
+				failures = append(failures, fmt.Sprintf("%s %s increased by %.1f%%", name, v.name, d))
+
+			case v.name == "error rate" && c > o.MaxErrorRate:
+				mark = "FAIL"
+				failures = append(failures, fmt.Sprintf("%s error rate is %.2f%%", name, c))
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.2f\t%s\t%s\n", name, v.name, b, c, deltaText, mark)
+		}
+	}
+	tw.Flush()
+
+	if len(warnings) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(warnings, "\n"))
+	}
+
+	return failures, nil
+}
+
+// metricNames returns the names of all metrics of both results. The metrics of
+// the baseline come first.
+func metricNames(baseline, candidate stats.Result) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range []stats.Result{baseline, candidate} {
+		for _, m := range r.Metrics {
+			if !seen[m.Name] {
+				seen[m.Name] = true
+				names = append(names, m.Name)
+			}
+		}
+	}
+	return names
+}
+
+// values are the values that are compared. The latency values are in
+// milliseconds and the error rate in percent.
+var values = []struct {
+	name string
+	get  func(stats.MetricResult) float64
+}{
+	{"count", func(m stats.MetricResult) float64 { return float64(m.Count) }},
+	{"error rate", func(m stats.MetricResult) float64 { return m.ErrorRate() * 100 }},
+	{"mean", func(m stats.MetricResult) float64 { return m.Mean }},
+	{"p50", func(m stats.MetricResult) float64 { return m.P50 }},
+	{"p90", func(m stats.MetricResult) float64 { return m.P90 }},
+	{"p95", func(m stats.MetricResult) float64 { return m.P95 }},
+	{"p99", func(m stats.MetricResult) float64 { return m.P99 }},
+	{"max", func(m stats.MetricResult) float64 { return m.Max }},
+}
+
+// delta returns the change from baseline to candidate in percent.
+//
+// If the baseline is 0 and the candidate is not, there is no relative change
+// and false is returned.
+func delta(baseline, candidate float64) (float64, bool) {
+	if baseline == 0 {
+		return 0, candidate == 0
+	}
+	return (candidate - baseline) / baseline * 100, true
+}
+
+// thresholds returns the maximum allowed increase in percent for each checked
+// latency value.
+func (o Options) thresholds() (map[string]float64, error) {
+	if len(o.Threshold) == 0 {
+		return map[string]float64{o.Percentile: o.MaxIncrease}, nil
+	}
+
+	for name := range o.Threshold {
+		if name == "count" || name == "error rate" || !isValue(name) {
+			return nil, fmt.Errorf("invalid threshold %q, use one of mean, p50, p90, p95, p99 or max", name)
+		}
+	}
+	return o.Threshold, nil
+}
+
+func isValue(name string) bool {
+	for _, v := range values {
+		if v.name == name {
+			return true
+		}
+	}
+	return false
+}
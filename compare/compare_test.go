@@ -0,0 +1,200 @@
+package compare
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+func TestDelta(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		baseline  float64
+		candidate float64
+		expect    float64
+		expectOK  bool
+	}{
+		{"increase", 100, 120, 20, true},
+		{"decrease", 100, 50, -50, true},
+		{"equal", 10, 10, 0, true},
+		{"both zero", 0, 0, 0, true},
+		{"new", 0, 5, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := delta(tt.baseline, tt.candidate)
+
+			if got != tt.expect || ok != tt.expectOK {
+				t.Errorf("delta(%f, %f) returned %f, %t, expected %f, %t", tt.baseline, tt.candidate, got, ok, tt.expect, tt.expectOK)
+			}
+		})
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		options   Options
+		expect    map[string]float64
+		expectErr bool
+	}{
+		{
+			"default",
+			Options{Percentile: "p95", MaxIncrease: 20},
+			map[string]float64{"p95": 20},
+			false,
+		},
+		{
+			"threshold",
+			Options{Percentile: "p95", MaxIncrease: 20, Threshold: map[string]float64{"p50": 5, "max": 50}},
+			map[string]float64{"p50": 5, "max": 50},
+			false,
+		},
+		{
+			"unknown value",
+			Options{Threshold: map[string]float64{"p42": 5}},
+			nil,
+			true,
+		},
+		{
+			"error rate",
+			Options{Threshold: map[string]float64{"error rate": 5}},
+			nil,
+			true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.thresholds()
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("thresholds() returned no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("thresholds(): %v", err)
+			}
+
+			if len(got) != len(tt.expect) {
+				t.Fatalf("got %v, expected %v", got, tt.expect)
+			}
+
+			for k, v := range tt.expect {
+				if got[k] != v {
+					t.Errorf("got %v, expected %v", got, tt.expect)
+				}
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	vote := stats.MetricResult{Name: "/system/vote", Count: 100, P50: 10, P95: 20}
+	login := stats.MetricResult{Name: "login", Count: 100, P50: 5, P95: 8}
+
+	options := Options{Percentile: "p95", MaxIncrease: 20, MaxErrorRate: 1}
+
+	for _, tt := range []struct {
+		name      string
+		options   Options
+		baseline  []stats.MetricResult
+		candidate stats.Result
+		expect    []string
+	}{
+		{
+			"equal",
+			options,
+			[]stats.MetricResult{vote, login},
+			stats.Result{Metrics: []stats.MetricResult{vote, login}},
+			nil,
+		},
+		{
+			"slower",
+			options,
+			[]stats.MetricResult{vote},
+			stats.Result{Metrics: []stats.MetricResult{{Name: "/system/vote", Count: 100, P50: 10, P95: 30}}},
+			[]string{"/system/vote p95 increased by 50.0%"},
+		},
+		{
+			"error rate",
+			options,
+			[]stats.MetricResult{vote},
+			stats.Result{Metrics: []stats.MetricResult{{Name: "/system/vote", Count: 98, Errors: map[string]int{"status 500": 2}, P50: 10, P95: 20}}},
+			[]string{"/system/vote error rate is 2.04%"},
+		},
+		{
+			"error rate below limit",
+			Options{Percentile: "p95", MaxIncrease: 20, MaxErrorRate: 5},
+			[]stats.MetricResult{vote},
+			stats.Result{Metrics: []stats.MetricResult{{Name: "/system/vote", Count: 98, Errors: map[string]int{"status 500": 2}, P50: 10, P95: 20}}},
+			nil,
+		},
+		{
+			"missing in candidate",
+			options,
+			[]stats.MetricResult{vote, login},
+			stats.Result{Metrics: []stats.MetricResult{vote}},
+			[]string{"login is missing in the candidate"},
+		},
+		{
+			"only in candidate",
+			options,
+			[]stats.MetricResult{vote},
+			stats.Result{Metrics: []stats.MetricResult{vote, login}},
+			nil,
+		},
+		{
+			"candidate failed",
+			options,
+			[]stats.MetricResult{vote},
+			stats.Result{Error: "connection refused", Metrics: []stats.MetricResult{vote}},
+			[]string{"candidate run failed: connection refused"},
+		},
+		{
+			"new latency",
+			Options{Threshold: map[string]float64{"p99": 10}, MaxErrorRate: 1},
+			[]stats.MetricResult{vote},
+			stats.Result{Metrics: []stats.MetricResult{{Name: "/system/vote", Count: 100, P50: 10, P95: 20, P99: 40}}},
+			[]string{"/system/vote p99 is 40.00 but 0 in the baseline"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.compare(io.Discard, stats.Result{Metrics: tt.baseline}, tt.candidate)
+			if err != nil {
+				t.Fatalf("compare: %v", err)
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tt.expect, "\n") {
+				t.Errorf("got failures %q, expected %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCompareWarnsAboutNewMetric(t *testing.T) {
+	vote := stats.MetricResult{Name: "/system/vote", Count: 100, P95: 20}
+	refresh := stats.MetricResult{Name: "token refresh", Count: 3, P95: 5}
+
+	buf := new(bytes.Buffer)
+	options := Options{Percentile: "p95", MaxIncrease: 20, MaxErrorRate: 1}
+	failures, err := options.compare(
+		buf,
+		stats.Result{Metrics: []stats.MetricResult{vote}},
+		stats.Result{Metrics: []stats.MetricResult{vote, refresh}},
+	)
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+
+	if len(failures) != 0 {
+		t.Errorf("got failures %q, expected none", failures)
+	}
+
+	if !strings.Contains(buf.String(), "Warning: token refresh is only in the candidate") {
+		t.Errorf("output has no warning for the new metric:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,37 @@
+package compare
+
+// Options is the meta information for the cli.
+type Options struct {
+	Baseline  string `arg:"" help:"Result file of the baseline run." type:"existingfile"`
+	Candidate string `arg:"" help:"Result file of the candidate run." type:"existingfile"`
+
+	Percentile   string             `help:"Latency value that is checked against --max-increase." default:"p95" enum:"mean,p50,p90,p95,p99,max"`
+	MaxIncrease  float64            `help:"Maximum allowed latency increase in percent." default:"20"`
+	Threshold    map[string]float64 `help:"Maximum allowed increase in percent of a latency value, e.g. p99=10. Can be used more then once. Replaces --percentile and --max-increase." placeholder:"VALUE=PERCENT"`
+	MaxErrorRate float64            `help:"Maximum allowed error rate of the candidate in percent." default:"1"`
+	Name         []string           `help:"Only check these metrics, e.g. /system/vote. Default are all metrics of both files." short:"n"`
+}
+
+// Help returns the help message
+func (o Options) Help() string {
+	return `Compares two result files created with --report. The files can be
+json or csv files.
+
+For each metric, the values of both runs and the difference is printed. The
+command fails, if the candidate is slower then allowed by --max-increase or
+has more errors then allowed by --max-error-rate. It also fails, if a metric
+of the baseline is missing in the candidate or if the candidate run failed. A
+metric, that is only in the candidate, is shown with a warning.
+
+Per default, only --percentile is checked. With --threshold, each latency
+value can get its own limit.
+
+If a value is 0 in the baseline, there is no relative change. The delta is
+shown as "new". If the value has a threshold, the check fails. The error rate
+is always checked with the absolute limit --max-error-rate.
+
+Example:
+
+openslides-performance compare baseline.json candidate.json --percentile p99 --max-increase 10
+openslides-performance compare baseline.json candidate.json --threshold p50=5 --threshold p99=20`
+}
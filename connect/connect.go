@@ -4,12 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/OpenSlides/openslides-performance/client"
@@ -28,6 +27,10 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		return fmt.Errorf("--body and --body-file are set at the same time. Only one is allowed")
 	}
 
+	if o.Herd > 0 && o.SkipFirst {
+		return fmt.Errorf("--herd can not be used with --skip-first, because the reconnected streams would not send their data")
+	}
+
 	body := `[{"collection":"organization","ids":[1],"fields":{"committee_ids":{"type":"relation-list","collection":"committee","fields":{"name":null}}}}]`
 	if o.BodyFile != nil {
 		bodyFileContent, err := io.ReadAll(o.BodyFile)
@@ -42,6 +45,19 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		body = o.Body
 	}
 
+	classes := []*class{newClass("", body, 1)}
+	if len(o.Mix) > 0 {
+		if o.Body != "" || o.BodyFile != nil {
+			return fmt.Errorf("--mix can not be used with --body or --body-file")
+		}
+
+		var err error
+		classes, err = parseMix(o.Mix)
+		if err != nil {
+			return fmt.Errorf("reading mix: %w", err)
+		}
+	}
+
 	var clients []*client.Client
 
 	if o.MultiUserMeeting == -1 {
@@ -66,16 +82,34 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		}
 
 		fmt.Println("login clients")
-		vote.MassLogin(ctx, clients, o.MultiUserMeeting, o.BaseName, o.UsersPassword)
-	}
-
-	actionCh := make(chan struct{})
-	if o.Action != nil {
-		content, err := io.ReadAll(o.Action)
+		failed, err := vote.MassLogin(ctx, clients, o.MultiUserMeeting, o.BaseName, o.UsersPassword, o.Login)
 		if err != nil {
-			return fmt.Errorf("reading action file: %w", err)
+			return fmt.Errorf("login clients: %w", err)
 		}
 
+		if len(failed) > 0 {
+			clients = failed.Remove(clients)
+			if len(clients) == 0 {
+				return fmt.Errorf("no client could log in")
+			}
+			log.Printf("%d logins failed, continue with %d connections: %s", len(failed), len(clients), failed)
+			o.Amount = len(clients)
+		}
+	}
+
+	// The classes are assigned after the logins, so the weights are used for
+	// the connections, that are opened.
+	connectionClasses := assignClasses(classes, o.Amount)
+
+	fan := newFanOut(o.SkipFirst)
+
+	actionBodies, err := o.actionBodies()
+	if err != nil {
+		return fmt.Errorf("reading actions: %w", err)
+	}
+
+	actionCh := make(chan struct{})
+	if len(actionBodies) > 0 {
 		c, err := client.New(cfg)
 		if err != nil {
 			return fmt.Errorf("creating client: %w", err)
@@ -86,71 +120,70 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		}
 
 		go func() {
-			listenForAction(ctx, c, content, actionCh)
+			if o.scriptedActions() {
+				o.scheduleActions(ctx, c, actionBodies, fan, actionCh)
+			} else {
+				listenForAction(ctx, c, actionBodies[0], fan, actionCh)
+			}
 			cancel()
 		}()
 	}
 
 	progress := mpb.New()
 	received := make(chan int, 1)
+	counter := new(reconnects)
+
+	h := &herd{stats: cfg.Stats}
+	conns := make([]*connection, o.Amount)
+	for i := range conns {
+		client := clients[0]
+		if o.MultiUserMeeting != -1 {
+			client = clients[i]
+		}
+
+		conns[i] = &connection{
+			id:       i,
+			client:   client,
+			class:    connectionClasses[i],
+			options:  o,
+			stats:    cfg.Stats,
+			fan:      fan,
+			counter:  counter,
+			herd:     h,
+			received: received,
+		}
+	}
 
+	ramp := cfg.Ramp(o.Amount)
 	for i := 0; i < o.Amount; i++ {
 		go func(i int) {
-			client := clients[0]
-			if o.MultiUserMeeting != -1 {
-				client = clients[i]
-			}
-			var r io.ReadCloser
-			for tries := 0; ; tries++ {
-				if tries > 100 {
-					return
-				}
-
-				skipFirstAttr := ""
-				if o.SkipFirst {
-					skipFirstAttr = "&skip_first=1"
-				}
-
-				var err error
-				r, err = keepOpen(ctx, client, "/system/autoupdate?compress=1"+skipFirstAttr, strings.NewReader(body))
-				if err != nil {
-					if ctx.Err() != nil {
-						return
-					}
-
-					log.Printf("Can not send request %d: %v", i, err)
-					time.Sleep(time.Second)
-					continue
-				}
-				break
+			connCtx, cancel, err := ramp.Wait(ctx, i)
+			if err != nil {
+				return
 			}
-			defer r.Close()
+			defer cancel()
 
-			scanner := bufio.NewScanner(r)
-			const MB = 1 << 20
-			scanner.Buffer(make([]byte, 10), 16*MB)
+			conns[i].run(connCtx)
 
-			changeID := 0
-			for scanner.Scan() {
-				received <- changeID
-				changeID++
-			}
-
-			if err := scanner.Err(); err != nil {
-				if errors.Is(err, context.Canceled) {
-					return
-				}
-				log.Printf("Can not read body: %v", err)
-				return
+			// The connection gave up or was closed by the ramp-down.
+			if ctx.Err() == nil {
+				conns[i].stopped()
 			}
 		}(i)
 	}
 
+	if o.Herd > 0 {
+		go h.run(ctx, o, conns)
+	}
+
 	var bars []*mpb.Bar
 
 	for {
 		select {
 		case <-ctx.Done():
+			printClasses(os.Stderr, classes)
+			fmt.Fprintln(os.Stderr, counter)
+			fan.Print(os.Stderr, o.Amount)
 			return nil
 		case <-actionCh:
 			bar := progress.AddBar(
@@ -191,7 +224,88 @@ func keepOpen(ctx context.Context, c *client.Client, path string, body io.Reader
 	return resp.Body, nil
 }
 
-func listenForAction(ctx context.Context, cli *client.Client, body []byte, actionCh chan struct{}) {
+// actionBodies returns the bodies of all actions from --action and
+// --actions-file.
+//
+// Each line of the actions file is one body. Empty lines are ignored.
+func (o Options) actionBodies() ([][]byte, error) {
+	var bodies [][]byte
+	if o.Action != nil {
+		content, err := io.ReadAll(o.Action)
+		if err != nil {
+			return nil, fmt.Errorf("reading action file: %w", err)
+		}
+		bodies = append(bodies, content)
+	}
+
+	if o.ActionsFile != nil {
+		scanner := bufio.NewScanner(o.ActionsFile)
+		const MB = 1 << 20
+		scanner.Buffer(make([]byte, 10), 16*MB)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			bodies = append(bodies, append([]byte(nil), line...))
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading actions file: %w", err)
+		}
+	}
+
+	return bodies, nil
+}
+
+// scriptedActions returns true, if the actions are sent automatically and not
+// with the enter key.
+func (o Options) scriptedActions() bool {
+	return o.ActionInterval > 0 || o.ActionCount > 0 || o.ActionsFile != nil
+}
+
+// scheduleActions sends the actions in the interval of --action-interval.
+//
+// The bodies are sent one after another. Without --action-count, each body of
+// the actions file is sent once and a single action is sent until the context
+// is done. After the last action, it waits one more interval, so the
+// connections can receive the change.
+func (o Options) scheduleActions(ctx context.Context, cli *client.Client, bodies [][]byte, fan *fanOut, actionCh chan struct{}) {
+	interval := o.ActionInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	count := o.ActionCount
+	if count <= 0 && o.ActionsFile != nil {
+		count = len(bodies)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; count <= 0 || i < count; i++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := sendAction(ctx, cli, bodies[i%len(bodies)], fan, actionCh); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("sending action: %v", err)
+			}
+			return
+		}
+	}
+
+	select {
+	case <-ticker.C:
+	case <-ctx.Done():
+	}
+}
+
+func listenForAction(ctx context.Context, cli *client.Client, body []byte, fan *fanOut, actionCh chan struct{}) {
 	if err := keyboard.Open(); err != nil {
 		log.Printf("open keyboard: %v", err)
 		return
@@ -213,14 +327,18 @@ func listenForAction(ctx context.Context, cli *client.Client, body []byte, actio
 			continue
 		}
 
-		if err := sendAction(ctx, cli, body, actionCh); err != nil {
+		if err := sendAction(ctx, cli, body, fan, actionCh); err != nil {
 			log.Printf("sending action: %v", err)
 			return
 		}
 	}
 }
 
-func sendAction(ctx context.Context, cli *client.Client, body []byte, actionCh chan struct{}) error {
+// sendAction sends the action to the backend.
+//
+// The time before the request is sent is used to measure the fan-out latency
+// of the change.
+func sendAction(ctx context.Context, cli *client.Client, body []byte, fan *fanOut, actionCh chan struct{}) error {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
@@ -232,10 +350,15 @@ func sendAction(ctx context.Context, cli *client.Client, body []byte, actionCh c
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	fan.send()
 	if _, err := cli.Do(req); err != nil {
 		return fmt.Errorf("sending request: %w", err)
 	}
 
-	actionCh <- struct{}{}
+	// Run does not read the channel anymore, when the context is done.
+	select {
+	case actionCh <- struct{}{}:
+	case <-ctx.Done():
+	}
 	return nil
 }
@@ -0,0 +1,331 @@
+package connect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+// metricReconnect is the name of the metric for the time from losing a
+// connection until the data is received again.
+const metricReconnect = "autoupdate reconnect"
+
+// connection is one autoupdate connection that is opened again, when it is
+// closed.
+type connection struct {
+	id       int
+	client   *client.Client
+	class    *class
+	options  Options
+	stats    *stats.Collector
+	fan      *fanOut
+	counter  *reconnects
+	herd     *herd
+	received chan<- int
+
+	mu           sync.Mutex
+	cancelStream context.CancelFunc
+
+	// dropped is true, if the connection was closed by the herd and not by
+	// the server.
+	dropped bool
+
+	// changeID is the number of the next change. It is not reset, when the
+	// connection is opened again.
+	changeID int
+
+	// disconnected is the time, when the connection was lost. It is zero, if
+	// the connection was not lost.
+	disconnected time.Time
+}
+
+// run keeps the connection open until the context is done or the connection
+// failed too often.
+func (c *connection) run(ctx context.Context) {
+	path := "/system/autoupdate?compress=1"
+	if c.options.SkipFirst {
+		path += "&skip_first=1"
+	}
+
+	failures := 0
+	for {
+		if failures > 0 && !c.pause(ctx, failures) {
+			return
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		c.setStream(cancel)
+
+		start := time.Now()
+		r, err := keepOpen(streamCtx, c.client, path, strings.NewReader(c.class.body))
+		if err != nil {
+			c.setStream(nil)
+			cancel()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("Can not send request %d: %v", c.id, err)
+			failures++
+			continue
+		}
+
+		if !c.disconnected.IsZero() && c.options.SkipFirst {
+			// With skip first, there is no first message to wait for.
+			c.reconnected(time.Now())
+		}
+
+		gotMessage, err := c.read(r, start)
+		r.Close()
+		c.setStream(nil)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && !c.isDropped() {
+			log.Printf("Connection %d lost: %v", c.id, err)
+		}
+
+		c.counter.disconnects.Add(1)
+		c.stats.Inc("connect_disconnects_total")
+		if c.disconnected.IsZero() {
+			c.disconnected = time.Now()
+		}
+
+		// If the connection was lost after it received data, the first
+		// attempt is sent without a pause. Otherwise the server closes the
+		// connection directly and it is handled like a failed attempt.
+		if !gotMessage {
+			failures++
+			continue
+		}
+
+		failures = 0
+		c.countAttempt()
+	}
+}
+
+// pause waits before the next attempt to open the connection.
+//
+// It returns false, if the context is done or the connection failed too
+// often.
+func (c *connection) pause(ctx context.Context, failures int) bool {
+	if failures > c.options.MaxAttempts {
+		c.counter.failed.Add(1)
+		c.stats.Inc("connect_failed_connections_total")
+		log.Printf("Connection %d failed after %d attempts", c.id, failures)
+		return false
+	}
+
+	timer := time.NewTimer(c.options.backoff(failures))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false
+	}
+
+	c.countAttempt()
+	return true
+}
+
+// setStream sets the function to close the current stream.
+func (c *connection) setStream(cancel context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelStream = cancel
+}
+
+// drop closes the current stream and registers it with the herd. It returns
+// false, if the connection is not open.
+func (c *connection) drop() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancelStream == nil {
+		return false
+	}
+
+	c.herd.add()
+	c.cancelStream()
+	c.cancelStream = nil
+	c.dropped = true
+	return true
+}
+
+func (c *connection) isDropped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.dropped
+}
+
+// reconnected is called, when the connection has its data again after it was
+// lost.
+func (c *connection) reconnected(at time.Time) {
+	c.stats.Add(metricReconnect, at.Sub(c.disconnected))
+	c.disconnected = time.Time{}
+
+	c.mu.Lock()
+	dropped := c.dropped
+	c.dropped = false
+	c.mu.Unlock()
+
+	if dropped {
+		c.herd.reconnected()
+	}
+}
+
+// stopped is called, when the connection stopped before the command ended. If
+// it was dropped and did not get its data again, it is released from the herd.
+func (c *connection) stopped() {
+	c.mu.Lock()
+	dropped := c.dropped
+	c.dropped = false
+	c.mu.Unlock()
+
+	if dropped {
+		c.herd.giveUp()
+	}
+}
+
+func (c *connection) countAttempt() {
+	c.counter.attempts.Add(1)
+	c.stats.Inc("connect_reconnect_attempts_total")
+}
+
+// read reads the messages from the connection until it is closed.
+//
+// start is the time, when the connection was opened. It returns true, if at
+// least one message was received.
+func (c *connection) read(r io.Reader, start time.Time) (bool, error) {
+	c.stats.AddGauge("connect_open_streams", 1)
+	defer c.stats.AddGauge("connect_open_streams", -1)
+
+	scanner := bufio.NewScanner(r)
+	const MB = 1 << 20
+	scanner.Buffer(make([]byte, 10), 16*MB)
+
+	first := true
+	gotMessage := false
+	for scanner.Scan() {
+		gotMessage = true
+		receivedAt := time.Now()
+		line := scanner.Bytes()
+
+		reconnected := first && !c.disconnected.IsZero() && !c.options.SkipFirst
+		first = false
+
+		if reconnected {
+			// The first response after a reconnect ends the disconnect, even
+			// if it is invalid. Otherwise the next reconnect would be
+			// measured from this disconnect.
+			c.reconnected(receivedAt)
+		}
+
+		decoded, err := c.class.decoder.decode(line)
+		if err != nil {
+			var errInvalid invalidMessageError
+			errors.As(err, &errInvalid)
+
+			c.stats.Inc("connect_invalid_messages_total", "reason", errInvalid.reason)
+			log.Printf("Connection %d: invalid message for change %d: %v", c.id, c.changeID+1, err)
+			if !reconnected {
+				c.class.payloads.add(c.changeID, len(line), len(decoded), errInvalid.reason)
+				c.changeID++
+			}
+			continue
+		}
+
+		if reconnected {
+			// The first message after a reconnect contains all data again. It
+			// does not belong to a change.
+			continue
+		}
+
+		c.class.payloads.add(c.changeID, len(line), len(decoded), "")
+
+		if c.changeID == 0 && !c.options.SkipFirst {
+			c.stats.Add(c.class.metric(metricInitialData), receivedAt.Sub(start))
+		}
+
+		if latency, ok := c.fan.receive(c.changeID, receivedAt); ok {
+			c.stats.Add(c.class.metric(metricFanOut), latency)
+		}
+
+		c.stats.Inc("connect_messages_total", "kind", c.messageKind())
+		c.received <- c.changeID
+		c.changeID++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return gotMessage, fmt.Errorf("reading body: %w", err)
+	}
+	return gotMessage, nil
+}
+
+// backoff returns the time to wait before the n-th attempt to open a
+// connection.
+//
+// The time grows exponentially from --backoff-min to --backoff-max. A random
+// jitter spreads the attempts of many connections.
+func (o Options) backoff(attempt int) time.Duration {
+	d := o.BackoffMin
+	for i := 1; i < attempt && d < o.BackoffMax; i++ {
+		d *= 2
+	}
+
+	if d > o.BackoffMax {
+		d = o.BackoffMax
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	// Use a random time between the half and the full duration.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// reconnects counts how often the connections were lost.
+type reconnects struct {
+	disconnects atomic.Int64
+	attempts    atomic.Int64
+	failed      atomic.Int64
+}
+
+// String returns a summary of the counters.
+func (r *reconnects) String() string {
+	return fmt.Sprintf(
+		"Disconnects: %d, reconnect attempts: %d, failed connections: %d",
+		r.disconnects.Load(),
+		r.attempts.Load(),
+		r.failed.Load(),
+	)
+}
+
+// messageKind returns the label of the current message for the prometheus
+// metrics. It has only two values, so long runs do not create a series for
+// each change.
+func (c *connection) messageKind() string {
+	if c.changeID == 0 && !c.options.SkipFirst {
+		return "initial"
+	}
+	return "update"
+}
@@ -0,0 +1,111 @@
+package connect
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+// metricFanOut is the name of the metric for the time from sending an action
+// until a connection received the change.
+const metricFanOut = "autoupdate fan-out"
+
+// metricInitialData is the name of the metric for the time from opening a
+// connection until the first data is received.
+const metricInitialData = "autoupdate initial data"
+
+// fanOut measures for each action, how long it takes until every connection
+// received the change.
+type fanOut struct {
+	mu sync.Mutex
+
+	// offset is the change id of the first action. Without skip first, the
+	// first change is the initial data.
+	offset int
+
+	sent      []time.Time
+	latencies map[int][]time.Duration
+}
+
+func newFanOut(skipFirst bool) *fanOut {
+	offset := 1
+	if skipFirst {
+		offset = 0
+	}
+
+	return &fanOut{
+		offset:    offset,
+		latencies: make(map[int][]time.Duration),
+	}
+}
+
+// send has to be called directly before an action is sent.
+func (f *fanOut) send() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, time.Now())
+}
+
+// receive saves, that a connection received a change at the given time.
+//
+// It returns the latency of the change. It returns false, if the change does
+// not belong to an action.
+func (f *fanOut) receive(changeID int, at time.Time) (time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	action := changeID - f.offset
+	if action < 0 || action >= len(f.sent) {
+		return 0, false
+	}
+
+	latency := at.Sub(f.sent[action])
+	f.latencies[changeID] = append(f.latencies[changeID], latency)
+	return latency, true
+}
+
+// Print writes a table with the latency of the first, median and last
+// connection for each change.
+//
+// amount is the number of connections, that should have received each change.
+func (f *fanOut) Print(w io.Writer, amount int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.sent) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\treceived\tfirst\tmedian\tp90\tlast\t")
+	for action := range f.sent {
+		changeID := action + f.offset
+
+		latencies := append([]time.Duration(nil), f.latencies[changeID]...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		if len(latencies) == 0 {
+			fmt.Fprintf(tw, "change %d\t0/%d\t-\t-\t-\t-\t\n", changeID+1, amount)
+			continue
+		}
+
+		fmt.Fprintf(
+			tw,
+			"change %d\t%d/%d\t%v\t%v\t%v\t%v\t\n",
+			changeID+1,
+			len(latencies),
+			amount,
+			stats.Round(latencies[0]),
+			stats.Round(latencies[len(latencies)/2]),
+			stats.Round(latencies[len(latencies)*9/10]),
+			stats.Round(latencies[len(latencies)-1]),
+		)
+	}
+	tw.Flush()
+}
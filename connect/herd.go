@@ -0,0 +1,142 @@
+package connect
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+// metricHerd is the name of the metric for the time from dropping the
+// connections until all of them received their data again.
+const metricHerd = "autoupdate herd recovery"
+
+// herd drops all connections at once and measures, how long it takes, until
+// all of them have received their data again.
+//
+// This is what happens, when the autoupdate service is restarted.
+type herd struct {
+	stats *stats.Collector
+
+	mu       sync.Mutex
+	start    time.Time
+	dropping bool
+	pending  int
+
+	// lost is the amount of dropped connections, that stopped before they
+	// received their data again.
+	lost int
+}
+
+// run drops the connections in the interval of --herd until the context is
+// done.
+func (h *herd) run(ctx context.Context, o Options, conns []*connection) {
+	ticker := time.NewTicker(o.Herd)
+	defer ticker.Stop()
+
+	waves := o.HerdWaves
+	if waves < 1 {
+		waves = 1
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		h.begin()
+		for wave := 0; wave < waves; wave++ {
+			if wave > 0 {
+				timer := time.NewTimer(o.HerdWavePause)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+
+			dropped := 0
+			for i := wave; i < len(conns); i += waves {
+				if conns[i].drop() {
+					dropped++
+				}
+			}
+			log.Printf("Dropped %d connections", dropped)
+		}
+		h.end()
+	}
+}
+
+func (h *herd) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.start = time.Now()
+	h.dropping = true
+	h.pending = 0
+	h.lost = 0
+}
+
+// add registers a connection, that is dropped. It has to be called before the
+// connection is closed, so a fast reconnect is counted.
+func (h *herd) add() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pending++
+}
+
+// end is called after the last wave was dropped.
+func (h *herd) end() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dropping = false
+	h.finish()
+}
+
+// reconnected is called, when a dropped connection received its data again.
+func (h *herd) reconnected() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending > 0 {
+		h.pending--
+	}
+	h.finish()
+}
+
+// giveUp is called, when a dropped connection stopped before it received its
+// data again, because it failed too often or was closed by the ramp-down.
+func (h *herd) giveUp() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending > 0 {
+		h.pending--
+	}
+	h.lost++
+	h.finish()
+}
+
+// finish records the time, when all connections have their data again. Has
+// to be called with the lock.
+func (h *herd) finish() {
+	if h.dropping || h.pending > 0 || h.start.IsZero() {
+		return
+	}
+
+	d := time.Since(h.start)
+	h.stats.Add(metricHerd, d)
+	if h.lost > 0 {
+		log.Printf("All connections received their data again after %v, %d connections gave up", d, h.lost)
+	} else {
+		log.Printf("All connections received their data again after %v", d)
+	}
+	h.start = time.Time{}
+}
@@ -0,0 +1,232 @@
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// invalidMessageError is returned for messages that are not valid autoupdate
+// data.
+type invalidMessageError struct {
+	reason string
+	msg    string
+}
+
+func (e invalidMessageError) Error() string {
+	return e.reason + ": " + e.msg
+}
+
+// decoder decodes and verifies the messages of an autoupdate stream.
+type decoder struct {
+	// collections are the collections, that are expected in the messages. If
+	// it is nil, all collections are allowed.
+	collections map[string]bool
+}
+
+// newDecoder creates a decoder that expects the collections from the request
+// body.
+func newDecoder(body string) decoder {
+	var request any
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return decoder{}
+	}
+
+	collections := make(map[string]bool)
+	findCollections(request, collections)
+	if len(collections) == 0 {
+		return decoder{}
+	}
+
+	return decoder{collections: collections}
+}
+
+// findCollections adds all values of the attribute "collection" of the
+// request body to collections.
+func findCollections(value any, collections map[string]bool) {
+	switch v := value.(type) {
+	case []any:
+		for _, e := range v {
+			findCollections(e, collections)
+		}
+
+	case map[string]any:
+		if collection, ok := v["collection"].(string); ok {
+			collections[collection] = true
+		}
+
+		for _, e := range v {
+			findCollections(e, collections)
+		}
+	}
+}
+
+// decode decodes one line of the autoupdate stream.
+//
+// It returns the decompressed json. If the message is an error or contains
+// keys that were not requested, an invalidMessageError is returned. In this
+// case, the decompressed json is also returned, if possible.
+func (d decoder) decode(line []byte) ([]byte, error) {
+	decoded, err := decompress(bytes.TrimSpace(line))
+	if err != nil {
+		return nil, invalidMessageError{"decode", err.Error()}
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(decoded, &data); err != nil {
+		return decoded, invalidMessageError{"decode", fmt.Sprintf("invalid json: %v", err)}
+	}
+
+	if rawErr, ok := data["error"]; ok {
+		return decoded, invalidMessageError{"error object", string(rawErr)}
+	}
+
+	for key := range data {
+		if err := d.checkKey(key); err != nil {
+			return decoded, invalidMessageError{"unexpected key", err.Error()}
+		}
+	}
+
+	return decoded, nil
+}
+
+// checkKey returns an error, if the key is not a fqfield of an expected
+// collection.
+func (d decoder) checkKey(key string) error {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("%s is not a fqfield", key)
+	}
+
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return fmt.Errorf("%s has an invalid id", key)
+	}
+
+	if d.collections != nil && !d.collections[parts[0]] {
+		return fmt.Errorf("%s is from collection %s, that was not requested", key, parts[0])
+	}
+
+	return nil
+}
+
+// decompress returns the json of a message.
+//
+// Uncompressed messages, like error objects, are returned as they are. The
+// autoupdate service sends compressed messages as base64 encoded gzip.
+func decompress(line []byte) ([]byte, error) {
+	if len(line) > 0 && line[0] == '{' {
+		return line, nil
+	}
+
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+	n, err := base64.StdEncoding.Decode(raw, line)
+	if err != nil {
+		return nil, fmt.Errorf("decoding base64: %w", err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(raw[:n]))
+	if err != nil {
+		return nil, fmt.Errorf("reading gzip: %w", err)
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading gzip: %w", err)
+	}
+	return decoded, nil
+}
+
+// payloads counts the size of the messages for each change.
+type payloads struct {
+	mu      sync.Mutex
+	changes []payloadSize
+}
+
+type payloadSize struct {
+	messages     int
+	invalid      map[string]int
+	compressed   int
+	decompressed int
+}
+
+// add saves the size of one message.
+//
+// reason is the reason, why the message was invalid. It is empty for valid
+// messages.
+func (p *payloads) add(changeID int, compressed, decompressed int, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.changes) <= changeID {
+		p.changes = append(p.changes, payloadSize{invalid: make(map[string]int)})
+	}
+
+	c := &p.changes[changeID]
+	c.messages++
+	c.compressed += compressed
+	c.decompressed += decompressed
+	if reason != "" {
+		c.invalid[reason]++
+	}
+}
+
+// Print writes a table with the average size of the messages of each change.
+func (p *payloads) Print(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.changes) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tmessages\tcompressed\tdecompressed\tinvalid\t")
+	for changeID, c := range p.changes {
+		if c.messages == 0 {
+			continue
+		}
+
+		reasons := make([]string, 0, len(c.invalid))
+		for reason, count := range c.invalid {
+			reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+		}
+		sort.Strings(reasons)
+
+		invalid := "0"
+		if len(reasons) > 0 {
+			invalid = strings.Join(reasons, ", ")
+		}
+
+		fmt.Fprintf(
+			tw,
+			"change %d\t%d\t%s\t%s\t%s\t\n",
+			changeID+1,
+			c.messages,
+			formatBytes(c.compressed/c.messages),
+			formatBytes(c.decompressed/c.messages),
+			invalid,
+		)
+	}
+	tw.Flush()
+}
+
+// formatBytes returns a human readable size.
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
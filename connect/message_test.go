@@ -0,0 +1,47 @@
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	dec := newDecoder(`[{"collection":"organization","ids":[1],"fields":{"committee_ids":{"type":"relation-list","collection":"committee","fields":{"name":null}}}}]`)
+
+	compress := func(data string) []byte {
+		buf := new(bytes.Buffer)
+		gz := gzip.NewWriter(buf)
+		gz.Write([]byte(data))
+		gz.Close()
+		return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+
+	for _, tt := range []struct {
+		name   string
+		line   []byte
+		reason string
+	}{
+		{"plain", []byte(`{"organization/1/committee_ids":[1]}`), ""},
+		{"gzip", compress(`{"committee/1/name":"foo"}`), ""},
+		{"garbage", []byte(`not base64!`), "decode"},
+		{"not gzip", []byte(base64.StdEncoding.EncodeToString([]byte(`{"committee/1/name":"foo"}`))), "decode"},
+		{"broken gzip", compress(`{"committee/1/name":"foo"}`)[:20], "decode"},
+		{"error", []byte(`{"error":{"type":"invalid","msg":"broken"}}`), "error object"},
+		{"unexpected collection", compress(`{"motion/1/title":"foo"}`), "unexpected key"},
+		{"no fqfield", []byte(`{"foo":"bar"}`), "unexpected key"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dec.decode(tt.line)
+
+			var errInvalid invalidMessageError
+			errors.As(err, &errInvalid)
+
+			if errInvalid.reason != tt.reason {
+				t.Errorf("got error %v, expected reason %q", err, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package connect
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// class is a group of connections that use the same request body.
+type class struct {
+	name     string
+	body     string
+	weight   int
+	decoder  decoder
+	payloads *payloads
+
+	// connections is the amount of connections of this class.
+	connections int
+}
+
+func newClass(name, body string, weight int) *class {
+	return &class{
+		name:     name,
+		body:     body,
+		weight:   weight,
+		decoder:  newDecoder(body),
+		payloads: new(payloads),
+	}
+}
+
+// metric returns the name of a metric for this class.
+func (c *class) metric(name string) string {
+	if c.name == "" {
+		return name
+	}
+	return name + " " + c.name
+}
+
+// parseMix reads the classes from the values of --mix.
+//
+// Each value is a path with an optional weight, e.g. delegate.json:70. If the
+// path is a directory, each file in the directory is a class with the weight.
+// The name of a class is the file name without the extension.
+func parseMix(values []string) ([]*class, error) {
+	var classes []*class
+	for _, value := range values {
+		path := value
+		weight := 1
+		if idx := strings.LastIndex(value, ":"); idx != -1 {
+			w, err := strconv.Atoi(value[idx+1:])
+			if err == nil {
+				path = value[:idx]
+				weight = w
+			}
+		}
+
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight of %s has to be positive", path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading directory %s: %w", path, err)
+			}
+
+			files = files[:0]
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+
+		for _, file := range files {
+			body, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", file, err)
+			}
+
+			name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			classes = append(classes, newClass(name, string(body), weight))
+		}
+	}
+
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("no request bodies found")
+	}
+
+	return classes, nil
+}
+
+// assignClasses returns the class for each of the n connections.
+//
+// The classes are mixed with a smooth weighted round robin, so that the
+// classes are also mixed, when the connections are started one after another.
+func assignClasses(classes []*class, n int) []*class {
+	total := 0
+	for _, c := range classes {
+		total += c.weight
+	}
+
+	current := make([]int, len(classes))
+	assigned := make([]*class, n)
+	for i := 0; i < n; i++ {
+		best := 0
+		for j, c := range classes {
+			current[j] += c.weight
+			if current[j] > current[best] {
+				best = j
+			}
+		}
+		current[best] -= total
+
+		assigned[i] = classes[best]
+		classes[best].connections++
+	}
+	return assigned
+}
+
+// printClasses writes the payload sizes of each class.
+func printClasses(w io.Writer, classes []*class) {
+	if len(classes) == 1 && classes[0].name == "" {
+		classes[0].payloads.Print(w)
+		return
+	}
+
+	sorted := append([]*class(nil), classes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	for _, c := range sorted {
+		fmt.Fprintf(w, "%s: %d connections\n", c.name, c.connections)
+		c.payloads.Print(w)
+	}
+}
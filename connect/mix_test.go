@@ -0,0 +1,23 @@
+package connect
+
+import "testing"
+
+func TestAssignClasses(t *testing.T) {
+	classes := []*class{
+		newClass("delegate", "", 70),
+		newClass("projector", "", 20),
+		newClass("admin", "", 10),
+	}
+
+	assigned := assignClasses(classes, 100)
+
+	for _, c := range classes {
+		if c.connections != c.weight {
+			t.Errorf("class %s got %d connections, expected %d", c.name, c.connections, c.weight)
+		}
+	}
+
+	if assigned[0].name != "delegate" || assigned[1].name == "delegate" && assigned[2].name == "delegate" && assigned[3].name == "delegate" {
+		t.Errorf("classes are not mixed: %s %s %s %s", assigned[0].name, assigned[1].name, assigned[2].name, assigned[3].name)
+	}
+}
@@ -1,17 +1,34 @@
 package connect
 
-import "os"
+import (
+	"os"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/vote"
+)
 
 // Options is the meta information for the cli.
 type Options struct {
-	Amount           int      `help:"Amount of connections to use." short:"n" default:"10"`
-	Body             string   `help:"Request Body." short:"b"`
-	BodyFile         *os.File `help:"Request Body from a file. Use - for stdin"`
-	Action           *os.File `help:"Request Body to use as an action. If set, press enter to sent the action"`
-	SkipFirst        bool     `help:"Use skip first flag to save traffic."`
-	MultiUserMeeting int      `help:"Use dummy user accounts from meeting. 0 For global dummys. Uses the same account as default." short:"m" default:"-1"`
-	BaseName         string   `help:"The name string that is concatenated with meeting id and user id, e.g. m1dummy1." default:"dummy"`
-	UsersPassword    string   `help:"The password used for all users" default:"pass"`
+	Amount           int           `help:"Amount of connections to use." short:"n" default:"10"`
+	Body             string        `help:"Request Body." short:"b"`
+	BodyFile         *os.File      `help:"Request Body from a file. Use - for stdin"`
+	Mix              []string      `help:"Weighted request bodies, e.g. delegate.json:70. If a directory is given, each file is used with the weight." placeholder:"PATH[:WEIGHT]"`
+	Action           *os.File      `help:"Request Body to use as an action. If set, press enter to sent the action"`
+	ActionInterval   time.Duration `help:"Send the action automatically in this interval instead of waiting for the enter key. Default is one second, if --action-count or --actions-file is set."`
+	ActionCount      int           `help:"Send the action this many times automatically and stop the command afterwards."`
+	ActionsFile      *os.File      `help:"File with one action request body per line. The actions are sent automatically one after another."`
+	SkipFirst        bool          `help:"Use skip first flag to save traffic."`
+	BackoffMin       time.Duration `help:"Pause before the second attempt to open a lost connection. The pause is doubled after each failed attempt." default:"500ms"`
+	BackoffMax       time.Duration `help:"Maximum pause between two attempts to open a connection." default:"30s"`
+	MaxAttempts      int           `help:"Give up a connection after this many failed attempts in a row." default:"100"`
+	Herd             time.Duration `help:"Drop all connections in this interval to simulate a restart of the autoupdate service."`
+	HerdWaves        int           `help:"Drop the connections in this many waves." default:"1"`
+	HerdWavePause    time.Duration `help:"Pause between two waves." default:"1s"`
+	MultiUserMeeting int           `help:"Use dummy user accounts from meeting. 0 For global dummys. Uses the same account as default." short:"m" default:"-1"`
+	BaseName         string        `help:"The name string that is concatenated with meeting id and user id, e.g. m1dummy1." default:"dummy"`
+	UsersPassword    string        `help:"The password used for all users" default:"pass"`
+
+	Login vote.LoginOptions `embed:""`
 }
 
 // Help returns the help message
@@ -27,6 +44,40 @@ openslides-performance connect -b '[{"collection":"organization","ids":[1],"fiel
 With --muli-user-meeeting each connection uses a different user account. 
 The accounts can be created with the "create-users" command. The attribute 
 needs the meeting id that was used to create the users. Use "0" when the 
-users where created without a meeting.
+users where created without a meeting. Users, that can not log in, are
+skipped. Use --login-max-failures to abort instead and --login-concurrency or
+--login-rate to limit the load on the auth service.
+
+With --mix, the connections use different request bodies. The weight
+decides, how many connections use each body. The statistics are shown for
+each body.
+
+openslides-performance connect -n 100 --mix delegate.json:70 --mix projector.json:20 --mix admin.json:10
+
+When a connection is lost, it is opened again. The first attempt is sent
+immediately, the next ones with an exponential backoff with jitter. At the
+end, the disconnects, reconnect attempts and failed connections are shown.
+
+With --herd, all connections are dropped at once in the given interval, like
+after a deploy of OpenSlides. The time until all connections received their
+data again is measured. With --herd-waves, the connections are dropped in
+waves. --herd can not be used with --skip-first.
+
+openslides-performance connect -n 1000 --herd 1m --herd-waves 4 --herd-wave-pause 5s
+
+Each message is decoded and checked. Messages that can not be decoded, that
+are error objects or that contain keys from collections that were not
+requested are logged and not counted. At the end, a table shows the average
+size of the messages for each change.
+
+With --action, the action is sent, when enter is pressed. With
+--action-interval, --action-count or --actions-file, the actions are sent
+automatically. This works without a terminal, e.g. in docker or CI.
+
+openslides-performance connect --actions-file actions.jsonl --action-interval 5s
+
+With an action, the time from sending the action until each connection
+received the change is measured. At the end, a table shows for each change
+the latency of the first, the median and the last connection.
 `
 }
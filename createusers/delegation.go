@@ -0,0 +1,132 @@
+package createusers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/OpenSlides/openslides-performance/client"
+)
+
+// delegate lets the last users delegate their vote to the other users.
+//
+// userIDs are the ids of all created users. The principals are distributed
+// evenly to the delegates.
+func (o Options) delegate(ctx context.Context, c *client.Client, userIDs []int) error {
+	principalCount := countPrincipals(len(userIDs), o.DelegateFraction)
+	if principalCount == 0 {
+		return nil
+	}
+
+	delegates := userIDs[:len(userIDs)-principalCount]
+	principals := userIDs[len(userIDs)-principalCount:]
+
+	meetingUserIDs, err := meetingUsers(ctx, c, o.MeetingID, delegates)
+	if err != nil {
+		return fmt.Errorf("fetching meeting users: %w", err)
+	}
+
+	data := make([]map[string]int, len(principals))
+	for i, principal := range principals {
+		delegate := delegates[i%len(delegates)]
+		meetingUserID, ok := meetingUserIDs[delegate]
+		if !ok {
+			return fmt.Errorf("user %d is not in meeting %d", delegate, o.MeetingID)
+		}
+
+		data[i] = map[string]int{
+			"id":                   principal,
+			"meeting_id":           o.MeetingID,
+			"vote_delegated_to_id": meetingUserID,
+		}
+	}
+
+	body, err := json.Marshal([]map[string]any{{"action": "user.update", "data": data}})
+	if err != nil {
+		return fmt.Errorf("encoding action: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/system/action/handle_request", strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	resp.Body.Close()
+
+	log.Printf("%d users delegated their vote to %d users", len(principals), min(len(principals), len(delegates)))
+	return nil
+}
+
+// countPrincipals returns the amount of users, that delegate their vote.
+//
+// At least one user is left as delegate, even when the fraction is rounded up
+// to all users.
+func countPrincipals(users int, fraction float64) int {
+	count := int(math.Round(fraction * float64(users)))
+	return max(min(count, users-1), 0)
+}
+
+// meetingUsers returns the meeting user ids of the users in a meeting.
+func meetingUsers(ctx context.Context, c *client.Client, meetingID int, userIDs []int) (map[int]int, error) {
+	ids, err := json.Marshal(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("encoding user ids: %w", err)
+	}
+
+	body := fmt.Sprintf(`[{
+			"collection": "user",
+			"ids": %s,
+			"fields":{
+				"meeting_user_ids": {
+					"type": "relation-list",
+					"collection": "meeting_user",
+					"fields": {
+						"user_id": null,
+						"meeting_id": null
+					}
+				}
+			}
+		}]`,
+		ids,
+	)
+	req, err := http.NewRequestWithContext(ctx, "GET", "/system/autoupdate?single=1", strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var keys map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, fmt.Errorf("parsing response body: %w", err)
+	}
+
+	meetingUserIDs := make(map[int]int, len(userIDs))
+	for key, value := range keys {
+		var id int
+		if _, err := fmt.Sscanf(key, "meeting_user/%d/meeting_id", &id); err != nil {
+			continue
+		}
+
+		var mID, userID int
+		json.Unmarshal(value, &mID)
+		json.Unmarshal(keys[fmt.Sprintf("meeting_user/%d/user_id", id)], &userID)
+		if mID == meetingID {
+			meetingUserIDs[userID] = id
+		}
+	}
+	return meetingUserIDs, nil
+}
@@ -0,0 +1,23 @@
+package createusers
+
+import "testing"
+
+func TestCountPrincipals(t *testing.T) {
+	for _, tt := range []struct {
+		users    int
+		fraction float64
+		expect   int
+	}{
+		{0, 0.5, 0},
+		{1, 0.5, 0},
+		{2, 0.5, 1},
+		{3, 0.9, 2},
+		{10, 0.2, 2},
+		{10, 0.99, 9},
+		{100, 0, 0},
+	} {
+		if got := countPrincipals(tt.users, tt.fraction); got != tt.expect {
+			t.Errorf("countPrincipals(%d, %v) = %d, expected %d", tt.users, tt.fraction, got, tt.expect)
+		}
+	}
+}
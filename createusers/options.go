@@ -8,6 +8,8 @@ type Options struct {
 	FirstID       int    `help:"First id to use. Usefull when additional users should be created." default:"1"`
 	BaseName      string `help:"The name string that is concatenated with meeting id and user id, e.g. m1dummy1." default:"dummy"`
 	UsersPassword string `help:"The password used for all users" default:"pass"`
+
+	DelegateFraction float64 `help:"Share of the users, that delegate their vote to one of the other users, e.g. 0.2. Needs --meeting-id."`
 }
 
 // Help returns the help message
@@ -18,5 +20,9 @@ require, that there a many users created at the server.
 Do not run this command against a productive instance. It will change
 the database.
 
-Each user is called dummy1, dummy2 etc and has the password "pass".`
+Each user is called dummy1, dummy2 etc and has the password "pass".
+
+With --delegate-fraction, the last users delegate their vote to the first
+users. For example with -n 10 and --delegate-fraction 0.2, the users 9 and 10
+delegate their vote to the users 1 and 2.`
 }
@@ -24,10 +24,18 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		return fmt.Errorf("login client: %w", err)
 	}
 
+	if o.DelegateFraction < 0 || o.DelegateFraction >= 1 {
+		return fmt.Errorf("delegate fraction has to be at least 0 and less then 1")
+	}
+
+	if o.DelegateFraction > 0 && o.MeetingID == 0 {
+		return fmt.Errorf("delegate fraction needs a meeting id")
+	}
+
 	namePrefix := ""
 	extraFields := ""
 	if o.MeetingID != 0 {
-		groupID, err := delegateGroup(ctx, c, o.MeetingID)
+		groupID, err := DelegateGroup(ctx, c, o.MeetingID)
 		if err != nil {
 			return fmt.Errorf("fetching delegated group: %w", err)
 		}
@@ -58,7 +66,10 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 	progress := mpb.New()
 	userBar := progress.AddBar(int64(o.Amount))
 
-	eg, ctx := errgroup.WithContext(ctx)
+	// userIDs are the ids of the created users in the order of their names.
+	userIDs := make([]int, o.Amount)
+
+	eg, egCtx := errgroup.WithContext(ctx)
 
 	for b := 0; b < batchCount; b++ {
 		b := b
@@ -92,7 +103,7 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 			)
 
 			req, err := http.NewRequestWithContext(
-				ctx,
+				egCtx,
 				"POST",
 				"/system/action/handle_request",
 				strings.NewReader(createBody),
@@ -102,9 +113,35 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 			}
 			req.Header.Set("Content-Type", "application/json")
 
-			if _, err := c.Do(req); err != nil {
+			resp, err := c.Do(req)
+			if err != nil {
 				return fmt.Errorf("sending request: %w", err)
 			}
+			defer resp.Body.Close()
+
+			var respBody struct {
+				Results [][]struct {
+					ID int `json:"id"`
+				} `json:"results"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+				return fmt.Errorf("decoding response: %w", err)
+			}
+
+			var created []struct {
+				ID int `json:"id"`
+			}
+			if len(respBody.Results) > 0 {
+				created = respBody.Results[0]
+			}
+
+			if len(created) != o.Batch {
+				return fmt.Errorf("got %d results for %d created users", len(created), o.Batch)
+			}
+
+			for i, result := range created {
+				userIDs[b*o.Batch+i] = result.ID
+			}
 			userBar.IncrBy(o.Batch)
 			return nil
 		})
@@ -115,10 +152,17 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 	}
 
 	userBar.Wait()
+
+	if o.DelegateFraction > 0 {
+		if err := o.delegate(ctx, c, userIDs); err != nil {
+			return fmt.Errorf("delegating votes: %w", err)
+		}
+	}
 	return nil
 }
 
-func delegateGroup(ctx context.Context, c *client.Client, meetingID int) (int, error) {
+// DelegateGroup returns the id of the group with the external id Delegates.
+func DelegateGroup(ctx context.Context, c *client.Client, meetingID int) (int, error) {
 	url := "/system/autoupdate?single=1"
 	body := fmt.Sprintf(`[{
 			"collection": "meeting",
@@ -0,0 +1,436 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// actionFunc handles one element of the data of an action. It returns the
+// result for this element.
+type actionFunc func(tx *transaction, data map[string]json.RawMessage) (any, error)
+
+// actions are the actions with a special implementation. All other actions
+// that end with .create, .update or .delete are handled generically.
+var actions = map[string]actionFunc{
+	"user.create":        userCreate,
+	"user.update":        userUpdate,
+	"topic.create":       topicCreate,
+	"topic.delete":       topicDelete,
+	"motion.create":      motionCreate,
+	"motion.reset_state": motionResetState,
+	"motion.set_state":   genericUpdate("motion"),
+	"poll.create":        pollCreate,
+	"poll.start":         pollSetState("created", "started", startCryptPoll),
+	"poll.stop":          pollSetState("started", "finished", countVotes),
+	"poll.publish":       pollSetState("finished", "published", nil),
+	"poll.reset":         pollReset,
+}
+
+// handleAction implements the handle_request route of the backend.
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := s.auth(w, r)
+	if !ok {
+		return
+	}
+
+	if userID == 0 {
+		writeJSONError(w, http.StatusUnauthorized, "Anonymous is not allowed to call actions.")
+		return
+	}
+
+	var body []struct {
+		Action string                       `json:"action"`
+		Data   []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid body: %v", err))
+		return
+	}
+
+	tx := s.begin()
+	results := make([][]any, len(body))
+	for i, action := range body {
+		fn, err := actionFunction(action.Action)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for _, data := range action.Data {
+			result, err := fn(tx, data)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Action %s: %v", action.Action, err))
+				return
+			}
+			results[i] = append(results[i], result)
+		}
+	}
+
+	response, err := json.Marshal(map[string]any{
+		"success": true,
+		"message": "Actions handled successfully",
+		"results": results,
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("encoding response: %v", err))
+		return
+	}
+
+	if !s.actionWorker {
+		tx.commit()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+		return
+	}
+
+	workerTX := s.begin()
+	workerID := workerTX.create("action_worker", map[string]any{
+		"name":    body[0].Action,
+		"state":   "running",
+		"created": time.Now().Unix(),
+	})
+	workerTX.commit()
+
+	go func() {
+		time.Sleep(s.latency)
+		tx.commit()
+
+		fqid := fmt.Sprintf("action_worker/%d", workerID)
+		doneTX := s.begin()
+		doneTX.set(fqid, "state", "end")
+		doneTX.set(fqid, "result", json.RawMessage(response))
+		doneTX.commit()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(
+		w,
+		`{"success":true,"message":"Action worker started","results":[[{"fqid":"action_worker/%d","name":"%s","written":true}]]}`,
+		workerID,
+		body[0].Action,
+	)
+}
+
+// actionFunction returns the function for an action name.
+func actionFunction(name string) (actionFunc, error) {
+	if fn, ok := actions[name]; ok {
+		return fn, nil
+	}
+
+	collection, method, _ := strings.Cut(name, ".")
+	switch method {
+	case "create":
+		return genericCreate(collection), nil
+	case "update":
+		return genericUpdate(collection), nil
+	case "delete":
+		return genericDelete(collection), nil
+	}
+	return nil, fmt.Errorf("action %s does not exist", name)
+}
+
+// genericCreate creates an object with all fields from the data.
+func genericCreate(collection string) actionFunc {
+	return func(tx *transaction, data map[string]json.RawMessage) (any, error) {
+		fields := make(map[string]any, len(data))
+		for field, value := range data {
+			fields[field] = value
+		}
+
+		id := tx.create(collection, fields)
+		return map[string]int{"id": id}, nil
+	}
+}
+
+// genericUpdate sets all fields from the data on the object with the id from
+// the data.
+func genericUpdate(collection string) actionFunc {
+	return func(tx *transaction, data map[string]json.RawMessage) (any, error) {
+		fqid, err := dataFQID(tx, collection, data)
+		if err != nil {
+			return nil, err
+		}
+
+		for field, value := range data {
+			if field == "id" {
+				continue
+			}
+			tx.set(fqid, field, value)
+		}
+		return nil, nil
+	}
+}
+
+// genericDelete deletes the object with the id from the data.
+func genericDelete(collection string) actionFunc {
+	return func(tx *transaction, data map[string]json.RawMessage) (any, error) {
+		fqid, err := dataFQID(tx, collection, data)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.delete(fqid)
+		return nil, nil
+	}
+}
+
+// dataFQID returns the fqid of the existing object from the field "id".
+func dataFQID(tx *transaction, collection string, data map[string]json.RawMessage) (string, error) {
+	var id int
+	if err := json.Unmarshal(data["id"], &id); err != nil {
+		return "", fmt.Errorf("invalid id %q: %w", data["id"], err)
+	}
+
+	fqid := fmt.Sprintf("%s/%d", collection, id)
+	if !tx.exists(fqid) {
+		return "", fmt.Errorf("model '%s' does not exist", fqid)
+	}
+	return fqid, nil
+}
+
+func userCreate(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	var user struct {
+		Username        string `json:"username"`
+		DefaultPassword string `json:"default_password"`
+	}
+	if err := decodeData(data, &user); err != nil {
+		return nil, err
+	}
+
+	if user.Username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+
+	tx.s.mu.Lock()
+	_, exists := tx.s.users[user.Username]
+	tx.s.mu.Unlock()
+	if exists {
+		return nil, fmt.Errorf("a user with the username %s already exists", user.Username)
+	}
+
+	fields := make(map[string]any, len(data))
+	for field, value := range data {
+		if field == "default_password" || field == "vote_delegated_to_id" {
+			continue
+		}
+		fields[field] = value
+	}
+
+	id := tx.create("user", fields)
+
+	if _, ok := data["meeting_id"]; ok {
+		if err := setMeetingUser(tx, id, data); err != nil {
+			return nil, err
+		}
+	}
+
+	// The user can login before the transaction is commited. This is good
+	// enough for a fake server.
+	tx.s.mu.Lock()
+	tx.s.users[user.Username] = fakeUser{id: id, password: user.DefaultPassword}
+	tx.s.mu.Unlock()
+
+	return map[string]int{"id": id}, nil
+}
+
+// userUpdate updates a user. If the data contains a meeting_id, the fields of
+// the meeting user are updated.
+func userUpdate(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	fqid, err := dataFQID(tx, "user", data)
+	if err != nil {
+		return nil, err
+	}
+
+	for field, value := range data {
+		if field == "id" || field == "vote_delegated_to_id" {
+			continue
+		}
+		tx.set(fqid, field, value)
+	}
+
+	if _, ok := data["meeting_id"]; ok {
+		var userID int
+		json.Unmarshal(data["id"], &userID)
+		if err := setMeetingUser(tx, userID, data); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// setMeetingUser creates or updates the meeting user of a user for the
+// meeting_id in data.
+//
+// The field vote_delegated_to_id is the meeting user, that votes for this
+// user.
+func setMeetingUser(tx *transaction, userID int, data map[string]json.RawMessage) error {
+	var user struct {
+		MeetingID         int   `json:"meeting_id"`
+		GroupIDs          []int `json:"group_ids"`
+		VoteDelegatedToID *int  `json:"vote_delegated_to_id"`
+	}
+	if err := decodeData(data, &user); err != nil {
+		return err
+	}
+
+	if !tx.exists(fmt.Sprintf("meeting/%d", user.MeetingID)) {
+		return fmt.Errorf("model 'meeting/%d' does not exist", user.MeetingID)
+	}
+
+	meetingUserID := findMeetingUser(tx.get, userID, user.MeetingID)
+	if meetingUserID == 0 {
+		meetingUserID = tx.create("meeting_user", map[string]any{
+			"user_id":    userID,
+			"meeting_id": user.MeetingID,
+		})
+
+		userFQID := fmt.Sprintf("user/%d", userID)
+		var meetingUserIDs []int
+		json.Unmarshal(tx.get(userFQID, "meeting_user_ids"), &meetingUserIDs)
+		tx.set(userFQID, "meeting_user_ids", append(meetingUserIDs, meetingUserID))
+	}
+
+	fqid := fmt.Sprintf("meeting_user/%d", meetingUserID)
+	if user.GroupIDs != nil {
+		tx.set(fqid, "group_ids", user.GroupIDs)
+	}
+
+	if user.VoteDelegatedToID == nil {
+		return nil
+	}
+
+	delegateID := *user.VoteDelegatedToID
+	delegateFQID := fmt.Sprintf("meeting_user/%d", delegateID)
+	if !tx.exists(delegateFQID) {
+		return fmt.Errorf("model '%s' does not exist", delegateFQID)
+	}
+
+	if delegateID == meetingUserID {
+		return fmt.Errorf("user %d can not delegate the vote to themselves", userID)
+	}
+
+	var oldDelegateID int
+	json.Unmarshal(tx.get(fqid, "vote_delegated_to_id"), &oldDelegateID)
+	if oldDelegateID != 0 {
+		return fmt.Errorf("user %d has already delegated the vote", userID)
+	}
+
+	var principals []int
+	json.Unmarshal(tx.get(delegateFQID, "vote_delegations_from_ids"), &principals)
+	tx.set(delegateFQID, "vote_delegations_from_ids", append(principals, meetingUserID))
+	tx.set(fqid, "vote_delegated_to_id", delegateID)
+	return nil
+}
+
+// findMeetingUser returns the id of the meeting user of a user in a meeting.
+// It returns 0, if the user is not in the meeting.
+func findMeetingUser(get func(fqid, field string) json.RawMessage, userID, meetingID int) int {
+	var meetingUserIDs []int
+	json.Unmarshal(get(fmt.Sprintf("user/%d", userID), "meeting_user_ids"), &meetingUserIDs)
+	for _, id := range meetingUserIDs {
+		var mID int
+		json.Unmarshal(get(fmt.Sprintf("meeting_user/%d", id), "meeting_id"), &mID)
+		if mID == meetingID {
+			return id
+		}
+	}
+	return 0
+}
+
+func topicCreate(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	var topic struct {
+		MeetingID int    `json:"meeting_id"`
+		Title     string `json:"title"`
+	}
+	if err := decodeData(data, &topic); err != nil {
+		return nil, err
+	}
+
+	if !tx.exists(fmt.Sprintf("meeting/%d", topic.MeetingID)) {
+		return nil, fmt.Errorf("model 'meeting/%d' does not exist", topic.MeetingID)
+	}
+
+	fields := make(map[string]any, len(data))
+	for field, value := range data {
+		fields[field] = value
+	}
+
+	id := tx.create("topic", fields)
+	agendaItemID := tx.create("agenda_item", map[string]any{
+		"meeting_id":        topic.MeetingID,
+		"content_object_id": fmt.Sprintf("topic/%d", id),
+		"closed":            false,
+	})
+	tx.set(fmt.Sprintf("topic/%d", id), "agenda_item_id", agendaItemID)
+
+	return map[string]int{"id": id}, nil
+}
+
+func topicDelete(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	fqid, err := dataFQID(tx, "topic", data)
+	if err != nil {
+		return nil, err
+	}
+
+	var agendaItemID int
+	if err := json.Unmarshal(tx.get(fqid, "agenda_item_id"), &agendaItemID); err == nil {
+		tx.delete(fmt.Sprintf("agenda_item/%d", agendaItemID))
+	}
+
+	tx.delete(fqid)
+	return nil, nil
+}
+
+func motionCreate(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	var motion struct {
+		MeetingID int `json:"meeting_id"`
+	}
+	if err := decodeData(data, &motion); err != nil {
+		return nil, err
+	}
+
+	if !tx.exists(fmt.Sprintf("meeting/%d", motion.MeetingID)) {
+		return nil, fmt.Errorf("model 'meeting/%d' does not exist", motion.MeetingID)
+	}
+
+	fields := make(map[string]any, len(data)+1)
+	for field, value := range data {
+		fields[field] = value
+	}
+	fields["state_id"] = 1
+
+	id := tx.create("motion", fields)
+	return map[string]int{"id": id}, nil
+}
+
+func motionResetState(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	fqid, err := dataFQID(tx, "motion", data)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.set(fqid, "state_id", 1)
+	return nil, nil
+}
+
+// decodeData decodes the data of an action into a struct.
+func decodeData(data map[string]json.RawMessage, v any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding data: %w", err)
+	}
+
+	if err := json.Unmarshal(encoded, v); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package fakeserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleLogin logs in a user with username and password.
+//
+// Like the auth service, it returns the access token in the header
+// "authentication" and sets the cookie "refreshId".
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	user, ok := s.users[body.Username]
+	s.mu.Unlock()
+
+	if !ok || user.password != body.Password {
+		writeJSONError(w, http.StatusForbidden, "Username or password is incorrect.")
+		return
+	}
+
+	cookie := "bearer " + randomString()
+	authToken := s.newToken(user.id)
+
+	s.mu.Lock()
+	s.cookies[cookie] = user.id
+	s.mu.Unlock()
+
+	w.Header().Set("authentication", authToken)
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refreshId",
+		Value:    cookie,
+		HttpOnly: true,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"success":true,"message":"Authentication successful!"}`)
+}
+
+// handleWhoAmI returns a new access token for the refresh cookie.
+func (s *Server) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("refreshId")
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "No refresh cookie.")
+		return
+	}
+
+	s.mu.Lock()
+	userID, ok := s.cookies[cookie.Value]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid refresh cookie.")
+		return
+	}
+
+	w.Header().Set("authentication", s.newToken(userID))
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"success":true,"message":"Authentication successful!"}`)
+}
+
+// token is an issued access token.
+type token struct {
+	userID  int
+	expires time.Time // Zero if the token does not expire.
+}
+
+// newToken creates an access token for the user.
+//
+// The token looks like a jwt token but is not signed.
+func (s *Server) newToken(userID int) string {
+	payload := fmt.Sprintf(`{"userId":%d,"sessionId":"%s"}`, userID, randomString())
+	authToken := fmt.Sprintf(
+		"bearer %s.%s.%s",
+		base64.RawStdEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)),
+		base64.RawStdEncoding.EncodeToString([]byte(payload)),
+		randomString(),
+	)
+
+	t := token{userID: userID}
+	if s.tokenLifetime > 0 {
+		t.expires = time.Now().Add(s.tokenLifetime)
+	}
+
+	s.mu.Lock()
+	s.tokens[authToken] = t
+	s.mu.Unlock()
+
+	return authToken
+}
+
+// auth returns the id of the user that sent the request. Returns 0 for an
+// anonymous request.
+//
+// If the request has an unknown or expired token, auth writes the status 401
+// and returns false.
+func (s *Server) auth(w http.ResponseWriter, r *http.Request) (int, bool) {
+	authToken := r.Header.Get("authentication")
+	if authToken == "" {
+		return 0, true
+	}
+
+	if !strings.HasPrefix(strings.ToLower(authToken), "bearer ") {
+		authToken = "bearer " + authToken
+	}
+
+	s.mu.Lock()
+	t, ok := s.tokens[authToken]
+	s.mu.Unlock()
+
+	if !ok || (!t.expires.IsZero() && time.Now().After(t.expires)) {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid or expired token.")
+		return 0, false
+	}
+
+	return t.userID, true
+}
+
+// writeJSONError writes an error like the backend does.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoded, _ := json.Marshal(message)
+	fmt.Fprintf(w, `{"success":false,"message":%s}`, encoded)
+}
@@ -0,0 +1,236 @@
+package fakeserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// keysRequest is one element of the body of an autoupdate request.
+type keysRequest struct {
+	Collection string                     `json:"collection"`
+	IDs        []int                      `json:"ids"`
+	Fields     map[string]json.RawMessage `json:"fields"`
+}
+
+// fieldRequest is the value of a field in a keysRequest that follows a
+// relation.
+type fieldRequest struct {
+	Type       string                     `json:"type"`
+	Collection string                     `json:"collection"`
+	Fields     map[string]json.RawMessage `json:"fields"`
+}
+
+// handleAutoupdate implements the autoupdate service.
+//
+// The keys can be requested with a body or with the query argument "k". The
+// query arguments "single", "compress" and "skip_first" are supported.
+func (s *Server) handleAutoupdate(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.auth(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	var requests []keysRequest
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, &requests); err != nil {
+				writeAutoupdateError(w, fmt.Sprintf("invalid body: %v", err))
+				return
+			}
+		}
+	}
+
+	var keys []string
+	if k := query.Get("k"); k != "" {
+		keys = strings.Split(k, ",")
+	}
+
+	single := query.Has("single")
+	compress := query.Has("compress")
+	skipFirst := query.Has("skip_first")
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	var last map[string]json.RawMessage
+	for first := true; ; first = false {
+		// Get the channel before the data, so no change is missed.
+		changed := s.waitForChange()
+
+		data := s.resolve(requests, keys)
+		message := data
+		if !first {
+			message = diff(last, data)
+		}
+		last = data
+
+		write := len(message) > 0 || first
+		if first && skipFirst && !single {
+			write = false
+		}
+
+		if write {
+			if err := writeAutoupdate(w, message, compress); err != nil {
+				return
+			}
+		}
+
+		if single {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// resolve returns all values that are requested by the keysRequests and the
+// keys.
+func (s *Server) resolve(requests []keysRequest, keys []string) map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make(map[string]json.RawMessage)
+	for _, key := range keys {
+		if value := s.get(key); value != nil {
+			data[key] = value
+		}
+	}
+
+	for _, req := range requests {
+		s.resolveRequest(data, req.Collection, req.IDs, req.Fields)
+	}
+	return data
+}
+
+// resolveRequest adds the values of the fields to data. Has to be called with
+// the lock.
+func (s *Server) resolveRequest(data map[string]json.RawMessage, collection string, ids []int, fields map[string]json.RawMessage) {
+	for _, id := range ids {
+		fqid := collection + "/" + strconv.Itoa(id)
+		object, ok := s.data[fqid]
+		if !ok {
+			continue
+		}
+
+		for field, rawSub := range fields {
+			value, ok := object[field]
+			if !ok {
+				continue
+			}
+
+			key := fqid + "/" + field
+			if _, ok := data[key]; ok {
+				// Already resolved. This also stops cycles.
+				continue
+			}
+			data[key] = value
+
+			var sub fieldRequest
+			if len(rawSub) == 0 || string(rawSub) == "null" || json.Unmarshal(rawSub, &sub) != nil {
+				continue
+			}
+
+			var subIDs []int
+			switch sub.Type {
+			case "relation":
+				var subID int
+				if err := json.Unmarshal(value, &subID); err != nil {
+					continue
+				}
+				subIDs = []int{subID}
+
+			case "relation-list":
+				if err := json.Unmarshal(value, &subIDs); err != nil {
+					continue
+				}
+			}
+
+			s.resolveRequest(data, sub.Collection, subIDs, sub.Fields)
+		}
+	}
+}
+
+// diff returns all values from new that are different in old. Values that
+// are not in new anymore are returned with the value null.
+func diff(old, new map[string]json.RawMessage) map[string]json.RawMessage {
+	changed := make(map[string]json.RawMessage)
+	for key, value := range new {
+		if !bytes.Equal(old[key], value) {
+			changed[key] = value
+		}
+	}
+
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changed[key] = json.RawMessage("null")
+		}
+	}
+	return changed
+}
+
+// writeAutoupdate writes one message as a line.
+//
+// Compressed messages are gzip compressed and base64 encoded.
+func writeAutoupdate(w http.ResponseWriter, data map[string]json.RawMessage, compress bool) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding data: %w", err)
+	}
+
+	if compress {
+		encoded, err = compressMessage(encoded)
+		if err != nil {
+			return fmt.Errorf("compressing data: %w", err)
+		}
+	}
+
+	if _, err := w.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("writing data: %w", err)
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func compressMessage(message []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(message); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(buf.Len()))
+	base64.StdEncoding.Encode(encoded, buf.Bytes())
+	return encoded, nil
+}
+
+// writeAutoupdateError writes an error like the autoupdate service does.
+func writeAutoupdateError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	encoded, _ := json.Marshal(message)
+	fmt.Fprintf(w, `{"error":{"type":"invalid","msg":%s}}`, encoded)
+}
@@ -0,0 +1,424 @@
+package fakeserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/connect"
+	"github.com/OpenSlides/openslides-performance/createusers"
+	"github.com/OpenSlides/openslides-performance/fakeserver"
+	"github.com/OpenSlides/openslides-performance/stats"
+	"github.com/OpenSlides/openslides-performance/vote"
+	"github.com/OpenSlides/openslides-performance/work"
+	"github.com/alecthomas/kong"
+)
+
+// commandConfig starts the fake server and returns the config for a command
+// that uses it.
+func commandConfig(t *testing.T, srv *fakeserver.Server) client.Config {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	return client.Config{
+		Domain:   ts.URL,
+		Username: "superadmin",
+		Password: "superadmin",
+		Stats:    stats.New(),
+	}
+}
+
+// parseOptions sets the options of a command from command line arguments.
+// This sets the defaults of all options that are not given.
+func parseOptions(t *testing.T, options any, args ...string) {
+	t.Helper()
+
+	parser, err := kong.New(options)
+	if err != nil {
+		t.Fatalf("creating parser: %v", err)
+	}
+
+	if _, err := parser.Parse(args); err != nil {
+		t.Fatalf("parsing arguments %v: %v", args, err)
+	}
+}
+
+// createUsers creates the users m1dummy1 to m1dummy{amount} in meeting 1.
+func createUsers(t *testing.T, cfg client.Config, amount int, args ...string) {
+	t.Helper()
+
+	var options createusers.Options
+	parseOptions(t, &options, append([]string{"-m", "1", "-n", strconv.Itoa(amount)}, args...)...)
+	if err := options.Run(context.Background(), cfg); err != nil {
+		t.Fatalf("create-users: %v", err)
+	}
+}
+
+// pollVotes returns the votes of the last created poll.
+func pollVotes(srv *fakeserver.Server) (int, map[int]json.RawMessage) {
+	pollID := 1
+	for srv.Get(fmt.Sprintf("poll/%d/state", pollID+1)) != nil {
+		pollID++
+	}
+	return pollID, srv.Votes(pollID)
+}
+
+func TestCommandVote(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		rounds int
+	}{
+		{"named", []string{"--create-poll", "named"}, 1},
+		{"multi option", []string{"--create-poll", "named", "--poll-method", "Y", "--poll-options", "3", "--distribution", "random"}, 1},
+		{"pseudoanonymous", []string{"--create-poll", "pseudoanonymous", "--distribution", "Y=50,N=50"}, 1},
+		{"negative", []string{"--create-poll", "named", "--negative", "2"}, 1},
+		{"rounds", []string{"--create-poll", "named", "--rounds", "3", "--round-pause", "1ms"}, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New()
+			cfg := commandConfig(t, srv)
+			createUsers(t, cfg, 10)
+
+			var options vote.Options
+			parseOptions(t, &options, append([]string{"-n", "10"}, tt.args...)...)
+
+			if err := options.Run(context.Background(), cfg); err != nil {
+				t.Fatalf("vote: %v", err)
+			}
+
+			pollID, votes := pollVotes(srv)
+			if len(votes) != 10 {
+				t.Errorf("poll %d got %d votes, expected 10", pollID, len(votes))
+			}
+
+			if state := string(srv.Get(fmt.Sprintf("poll/%d/state", pollID))); state != `"published"` {
+				t.Errorf("poll %d has state %s, expected published", pollID, state)
+			}
+
+			// Negative probes are not recorded as votes.
+			if got := cfg.Stats.Histogram("/system/vote").Count(); got != int64(10*tt.rounds) {
+				t.Errorf("got %d recorded votes, expected %d", got, 10*tt.rounds)
+			}
+
+			if got := cfg.Stats.Histogram("vote round").Count(); tt.rounds > 1 && got != int64(tt.rounds) {
+				t.Errorf("got %d rounds, expected %d", got, tt.rounds)
+			}
+		})
+	}
+}
+
+func TestCommandVoteVotedChecks(t *testing.T) {
+	srv := fakeserver.New()
+	cfg := commandConfig(t, srv)
+	createUsers(t, cfg, 10)
+
+	var options vote.Options
+	parseOptions(t, &options, "-n", "10", "--create-poll", "named", "--voted-interval", "20ms", "--profile", "spread", "--spread", "300ms")
+
+	if err := options.Run(context.Background(), cfg); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+
+	if got := cfg.Stats.Histogram("/system/vote/voted").Count(); got < 10 {
+		t.Errorf("got %d voted checks, expected at least one per user", got)
+	}
+}
+
+func TestCommandVoteDelegation(t *testing.T) {
+	srv := fakeserver.New()
+	cfg := commandConfig(t, srv)
+	createUsers(t, cfg, 10, "--delegate-fraction", "0.2")
+
+	var options vote.Options
+	parseOptions(t, &options, "-n", "10", "--create-poll", "named", "--delegation")
+
+	if err := options.Run(context.Background(), cfg); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+
+	pollID, votes := pollVotes(srv)
+	if len(votes) != 10 {
+		t.Errorf("poll %d got %d votes, expected 10 with the delegated votes", pollID, len(votes))
+	}
+}
+
+func TestCommandWork(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+	}{
+		{"topic done", []string{"-n", "2", "--strategy", "topic-done"}},
+		{"motion state", []string{"-n", "2", "--strategy", "motion-state"}},
+		{"rate", []string{"-n", "2", "--rate", "50/s"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New()
+			cfg := commandConfig(t, srv)
+
+			var options work.Options
+			parseOptions(t, &options, tt.args...)
+
+			cfg.Duration = 300 * time.Millisecond
+			ctx, cancel := cfg.WithDuration(context.Background())
+			defer cancel()
+
+			if err := options.Run(ctx, cfg); err != nil {
+				t.Fatalf("work: %v", err)
+			}
+
+			h := cfg.Stats.Histogram("/system/action/handle_request")
+			if h.Count() < 4 {
+				t.Errorf("got %d actions, expected at least 4", h.Count())
+			}
+
+			for _, m := range cfg.Stats.Metrics() {
+				if m.ErrorCount() > 0 {
+					t.Errorf("metric %s has errors: %v", m.Name, m.Errors)
+				}
+			}
+
+			// The workers delete their objects at the end.
+			for _, fqfield := range []string{"topic/1/title", "topic/2/title", "motion/1/title", "motion/2/title"} {
+				if value := srv.Get(fqfield); value != nil {
+					t.Errorf("%s still exists with %s", fqfield, value)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandWorkWithoutAmount(t *testing.T) {
+	cfg := commandConfig(t, fakeserver.New())
+
+	var options work.Options
+	parseOptions(t, &options, "-n", "0", "--rate", "10/s")
+
+	if err := options.Run(context.Background(), cfg); err == nil {
+		t.Errorf("work with --amount 0 did not return an error")
+	}
+}
+
+func TestCommandConnect(t *testing.T) {
+	srv := fakeserver.New()
+	cfg := commandConfig(t, srv)
+	createUsers(t, cfg, 5)
+
+	actionsPath := filepath.Join(t.TempDir(), "actions.jsonl")
+	actions := `[{"action":"committee.update","data":[{"id":1,"name":"first"}]}]
+[{"action":"committee.update","data":[{"id":1,"name":"second"}]}]`
+	if err := os.WriteFile(actionsPath, []byte(actions), 0o600); err != nil {
+		t.Fatalf("writing actions file: %v", err)
+	}
+
+	var options connect.Options
+	parseOptions(
+		t,
+		&options,
+		"-n", "5",
+		"-m", "1",
+		"--actions-file", actionsPath,
+		"--action-interval", "100ms",
+	)
+	t.Cleanup(func() { options.ActionsFile.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := options.Run(ctx, cfg); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatalf("connect did not stop after the actions")
+	}
+
+	if got := cfg.Stats.Histogram("autoupdate initial data").Count(); got != 5 {
+		t.Errorf("got initial data for %d connections, expected 5", got)
+	}
+
+	if got := cfg.Stats.Histogram("autoupdate fan-out").Count(); got != 10 {
+		t.Errorf("got %d received changes, expected 10 for 2 changes on 5 connections", got)
+	}
+
+	var metrics strings.Builder
+	cfg.Stats.WritePrometheus(&metrics)
+	for _, expect := range []string{
+		`openslides_performance_connect_messages_total{kind="initial"} 5`,
+		`openslides_performance_connect_messages_total{kind="update"} 10`,
+	} {
+		if !strings.Contains(metrics.String(), expect) {
+			t.Errorf("prometheus metrics do not contain %s:\n%s", expect, metrics.String())
+		}
+	}
+
+	if name := string(srv.Get("committee/1/name")); name != `"second"` {
+		t.Errorf("committee has name %s, expected the name from the last action", name)
+	}
+}
+
+func TestCommandConnectHerd(t *testing.T) {
+	cfg := commandConfig(t, fakeserver.New())
+
+	var options connect.Options
+	parseOptions(t, &options, "-n", "5", "--herd", "200ms", "--herd-waves", "2", "--herd-wave-pause", "10ms")
+
+	cfg.Duration = 700 * time.Millisecond
+	ctx, cancel := cfg.WithDuration(context.Background())
+	defer cancel()
+
+	if err := options.Run(ctx, cfg); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	if got := cfg.Stats.Histogram("autoupdate herd recovery").Count(); got < 2 {
+		t.Errorf("got %d herd recoveries, expected at least 2", got)
+	}
+
+	if got := cfg.Stats.Histogram("autoupdate reconnect").Count(); got < 10 {
+		t.Errorf("got %d reconnects, expected at least 10", got)
+	}
+}
+
+func TestCommandConnectHerdWithFailingReconnect(t *testing.T) {
+	srv := fakeserver.New()
+
+	// The two connections are opened and one of them can reconnect after
+	// the herd. All other requests to the autoupdate service fail.
+	var streams atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/system/autoupdate" && streams.Add(1) > 3 {
+			http.Error(w, "autoupdate is down", http.StatusServiceUnavailable)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	cfg := client.Config{
+		Domain:   ts.URL,
+		Username: "superadmin",
+		Password: "superadmin",
+		Stats:    stats.New(),
+	}
+
+	var options connect.Options
+	parseOptions(t, &options, "-n", "2", "--herd", "200ms", "--max-attempts", "1", "--backoff-min", "1ms")
+
+	cfg.Duration = 350 * time.Millisecond
+	ctx, cancel := cfg.WithDuration(context.Background())
+	defer cancel()
+
+	if err := options.Run(ctx, cfg); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	if got := cfg.Stats.Histogram("autoupdate herd recovery").Count(); got != 1 {
+		t.Errorf("got %d herd recoveries, expected 1", got)
+	}
+}
+
+func TestCommandConnectHerdWithSkipFirst(t *testing.T) {
+	cfg := commandConfig(t, fakeserver.New())
+
+	var options connect.Options
+	parseOptions(t, &options, "--herd", "1s", "--skip-first")
+
+	if err := options.Run(context.Background(), cfg); err == nil {
+		t.Errorf("connect with --herd and --skip-first did not return an error")
+	}
+}
+
+func TestCommandCreateUsers(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options []fakeserver.Option
+	}{
+		{"direct", nil},
+		{"action worker", []fakeserver.Option{fakeserver.WithActionWorker()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New(tt.options...)
+			cfg := commandConfig(t, srv)
+			createUsers(t, cfg, 10, "--batch", "5", "--delegate-fraction", "0.2")
+
+			// With -n 10 and --delegate-fraction 0.2, the users 9 and 10
+			// delegate their vote to the users 1 and 2.
+			for _, username := range []string{"m1dummy9", "m1dummy10"} {
+				if !hasDelegation(srv, username) {
+					t.Errorf("user %s did not delegate the vote", username)
+				}
+			}
+		})
+	}
+}
+
+// hasDelegation returns true, if the meeting user of the user in meeting 1
+// delegated the vote.
+func hasDelegation(srv *fakeserver.Server, username string) bool {
+	for id := 1; srv.Get(fmt.Sprintf("user/%d/username", id)) != nil; id++ {
+		if string(srv.Get(fmt.Sprintf("user/%d/username", id))) != strconv.Quote(username) {
+			continue
+		}
+
+		var meetingUserIDs []int
+		json.Unmarshal(srv.Get(fmt.Sprintf("user/%d/meeting_user_ids", id)), &meetingUserIDs)
+		for _, muID := range meetingUserIDs {
+			if srv.Get(fmt.Sprintf("meeting_user/%d/vote_delegated_to_id", muID)) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestCommandConnectMixWithFailedLogins(t *testing.T) {
+	cfg := commandConfig(t, fakeserver.New())
+
+	// Only 6 of the 10 users exist, so 4 logins fail.
+	createUsers(t, cfg, 6)
+
+	dir := t.TempDir()
+	body := `[{"collection":"organization","ids":[1],"fields":{"committee_ids":null}}]`
+	for _, name := range []string{"delegate.json", "projector.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatalf("writing body file: %v", err)
+		}
+	}
+
+	var options connect.Options
+	parseOptions(
+		t,
+		&options,
+		"-n", "10",
+		"-m", "1",
+		"--mix", filepath.Join(dir, "delegate.json")+":50",
+		"--mix", filepath.Join(dir, "projector.json")+":50",
+	)
+
+	cfg.Duration = 300 * time.Millisecond
+	ctx, cancel := cfg.WithDuration(context.Background())
+	defer cancel()
+
+	if err := options.Run(ctx, cfg); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	for _, class := range []string{"delegate", "projector"} {
+		if got := cfg.Stats.Histogram("autoupdate initial data " + class).Count(); got != 3 {
+			t.Errorf("class %s got %d connections, expected 3", class, got)
+		}
+	}
+}
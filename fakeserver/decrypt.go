@@ -0,0 +1,155 @@
+package fakeserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/vote-decrypt/crypto"
+	"github.com/OpenSlides/vote-decrypt/decrypt"
+	"github.com/OpenSlides/vote-decrypt/errorcode"
+)
+
+// WithDecrypt lets the server handle cryptographic polls with a local
+// vote-decrypt service.
+//
+// When a cryptographic poll is started, the poll gets a crypt key. When it is
+// stopped, the votes are decrypted and signed with the main key, that is
+// published in the field organization/1/vote_decrypt_public_main_key.
+func WithDecrypt() Option {
+	return func(s *Server) {
+		mainKey := make([]byte, 32)
+		if _, err := rand.Read(mainKey); err != nil {
+			panic(fmt.Sprintf("creating main key: %v", err))
+		}
+
+		s.decrypt = decrypt.New(crypto.New(mainKey, rand.Reader, nil), newMemoryStore())
+	}
+}
+
+// startCryptPoll creates the crypt key for a cryptographic poll.
+func startCryptPoll(tx *transaction, pollID int) error {
+	if tx.s.decrypt == nil || !isCryptographic(tx, pollID) {
+		return nil
+	}
+
+	pubKey, pubKeySig, err := tx.s.decrypt.Start(context.Background(), strconv.Itoa(pollID))
+	if err != nil {
+		return fmt.Errorf("starting poll in vote decrypt: %w", err)
+	}
+
+	fqid := fmt.Sprintf("poll/%d", pollID)
+	tx.set(fqid, "crypt_key", pubKey)
+	tx.set(fqid, "crypt_signature", pubKeySig)
+	return nil
+}
+
+// decryptVotes decrypts the votes of a cryptographic poll. It saves the
+// decrypted votes and the signature in the poll and returns the votes.
+func decryptVotes(tx *transaction, pollID int, votes map[int]json.RawMessage) ([]json.RawMessage, error) {
+	voteList := make([][]byte, 0, len(votes))
+	for _, vote := range votes {
+		var encrypted []byte
+		if err := json.Unmarshal(vote, &encrypted); err != nil {
+			// The vote is not encrypted. vote-decrypt returns an error value
+			// for it.
+			encrypted = vote
+		}
+		voteList = append(voteList, encrypted)
+	}
+
+	start := time.Now()
+	content, signature, err := tx.s.decrypt.Stop(context.Background(), strconv.Itoa(pollID), voteList)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting votes: %w", err)
+	}
+	log.Printf("Decrypted %d votes of poll %d in %v", len(voteList), pollID, time.Since(start))
+
+	if err := tx.s.decrypt.Clear(context.Background(), strconv.Itoa(pollID)); err != nil {
+		return nil, fmt.Errorf("clearing poll in vote decrypt: %w", err)
+	}
+
+	var decrypted struct {
+		Votes []json.RawMessage `json:"votes"`
+	}
+	if err := json.Unmarshal(content, &decrypted); err != nil {
+		return nil, fmt.Errorf("decoding decrypted votes: %w", err)
+	}
+
+	fqid := fmt.Sprintf("poll/%d", pollID)
+	tx.set(fqid, "votes_raw", string(content))
+	tx.set(fqid, "votes_signature", signature)
+	return decrypted.Votes, nil
+}
+
+func isCryptographic(tx *transaction, pollID int) bool {
+	var pollType string
+	json.Unmarshal(tx.get(fmt.Sprintf("poll/%d", pollID), "type"), &pollType)
+	return pollType == "cryptographic"
+}
+
+// memoryStore implements the store of vote-decrypt in memory.
+type memoryStore struct {
+	mu         sync.Mutex
+	keys       map[string][]byte
+	signatures map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		keys:       make(map[string][]byte),
+		signatures: make(map[string][]byte),
+	}
+}
+
+func (m *memoryStore) SaveKey(id string, key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[id]; ok {
+		return errorcode.Exist
+	}
+	m.keys[id] = key
+	return nil
+}
+
+func (m *memoryStore) LoadKey(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, errorcode.NotExist
+	}
+	return key, nil
+}
+
+func (m *memoryStore) ValidateSignature(id string, signature []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[id]; !ok {
+		return errorcode.NotExist
+	}
+
+	if old, ok := m.signatures[id]; ok && !bytes.Equal(old, signature) {
+		return errorcode.Invalid
+	}
+	m.signatures[id] = signature
+	return nil
+}
+
+func (m *memoryStore) ClearPoll(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, id)
+	delete(m.signatures, id)
+	return nil
+}
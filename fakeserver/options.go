@@ -0,0 +1,35 @@
+package fakeserver
+
+import "time"
+
+// Options is the meta information for the cli.
+type Options struct {
+	Port          int           `arg:"" help:"Port to use for the server. Default is 8000." default:"8000"`
+	Latency       time.Duration `help:"Delay for each response." short:"l"`
+	ErrorRate     float64       `help:"Share of requests that fail with status 500. Has to be between 0 and 1."`
+	ActionWorker  bool          `help:"Handle all actions with an action worker."`
+	TokenLifetime time.Duration `help:"Lifetime of the auth tokens. Default is no expiry."`
+	Decrypt       bool          `help:"Encrypt and decrypt cryptographic polls with a local vote-decrypt service."`
+}
+
+// Help returns the help message
+func (o Options) Help() string {
+	return `Starts a fake OpenSlides server that holds all data in memory.
+
+The server supports the login, the autoupdate service, the actions that are
+used by the commands of this tool and the vote service. It contains the user
+"superadmin" with the password "superadmin", a meeting with the id 1 and a
+started poll with the id 1.
+
+With --decrypt, cryptographic polls get a crypt key when they are started.
+When they are stopped, the votes are decrypted and signed like the
+vote-decrypt service does.
+
+The server uses https with a self signed certificate. Use --http for http.
+
+Example:
+
+openslides-performance fake-server
+openslides-performance create-users -m 1 -n 100
+openslides-performance vote -n 100 -i 1`
+}
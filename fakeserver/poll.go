@@ -0,0 +1,272 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// pollCreate creates a poll with its options.
+//
+// Analog polls get their results from the options and are finished at once.
+// All other polls have to be started.
+func pollCreate(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	var poll struct {
+		MeetingID          int    `json:"meeting_id"`
+		Type               string `json:"type"`
+		PublishImmediately bool   `json:"publish_immediately"`
+		Options            []struct {
+			Text string          `json:"text"`
+			Y    json.RawMessage `json:"Y"`
+			N    json.RawMessage `json:"N"`
+			A    json.RawMessage `json:"A"`
+		} `json:"options"`
+	}
+	if err := decodeData(data, &poll); err != nil {
+		return nil, err
+	}
+
+	if !tx.exists(fmt.Sprintf("meeting/%d", poll.MeetingID)) {
+		return nil, fmt.Errorf("model 'meeting/%d' does not exist", poll.MeetingID)
+	}
+
+	switch poll.Type {
+	case "analog", "named", "pseudoanonymous", "cryptographic":
+	default:
+		return nil, fmt.Errorf("invalid poll type %q", poll.Type)
+	}
+
+	if len(poll.Options) == 0 {
+		return nil, fmt.Errorf("poll needs at least one option")
+	}
+
+	fields := make(map[string]any, len(data)+1)
+	for field, value := range data {
+		if field == "options" || field == "publish_immediately" {
+			continue
+		}
+		fields[field] = value
+	}
+
+	fields["state"] = "created"
+	if poll.Type == "analog" {
+		fields["state"] = "finished"
+		if poll.PublishImmediately {
+			fields["state"] = "published"
+		}
+	}
+
+	pollID := tx.create("poll", fields)
+
+	optionIDs := make([]int, len(poll.Options))
+	for i, option := range poll.Options {
+		optionFields := map[string]any{
+			"poll_id":    pollID,
+			"meeting_id": poll.MeetingID,
+			"text":       option.Text,
+			"weight":     i + 1,
+		}
+
+		if poll.Type == "analog" {
+			optionFields["yes"] = decimal(option.Y)
+			optionFields["no"] = decimal(option.N)
+			optionFields["abstain"] = decimal(option.A)
+		}
+
+		optionIDs[i] = tx.create("option", optionFields)
+	}
+
+	globalOptionID := tx.create("option", map[string]any{
+		"used_as_global_option_in_poll_id": pollID,
+		"meeting_id":                       poll.MeetingID,
+		"text":                             "global option",
+	})
+
+	fqid := fmt.Sprintf("poll/%d", pollID)
+	tx.set(fqid, "option_ids", optionIDs)
+	tx.set(fqid, "global_option_id", globalOptionID)
+
+	return map[string]int{"id": pollID}, nil
+}
+
+// pollSetState returns an action that changes the state of a poll.
+//
+// If the poll is not in the state from, an error is returned. fn is called
+// after the state was changed.
+func pollSetState(from, to string, fn func(tx *transaction, pollID int) error) actionFunc {
+	return func(tx *transaction, data map[string]json.RawMessage) (any, error) {
+		fqid, err := dataFQID(tx, "poll", data)
+		if err != nil {
+			return nil, err
+		}
+
+		var state string
+		json.Unmarshal(tx.get(fqid, "state"), &state)
+		if state != from {
+			return nil, fmt.Errorf("poll is in state %s, expected %s", state, from)
+		}
+
+		tx.set(fqid, "state", to)
+
+		if fn != nil {
+			var pollID int
+			json.Unmarshal(data["id"], &pollID)
+			if err := fn(tx, pollID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+}
+
+// pollReset sets a poll back to the state created and deletes its votes and
+// results.
+func pollReset(tx *transaction, data map[string]json.RawMessage) (any, error) {
+	fqid, err := dataFQID(tx, "poll", data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pollID int
+	json.Unmarshal(data["id"], &pollID)
+
+	var pollType string
+	json.Unmarshal(tx.get(fqid, "type"), &pollType)
+	if pollType == "analog" {
+		return nil, fmt.Errorf("analog polls can not be reset")
+	}
+
+	tx.set(fqid, "state", "created")
+	for _, field := range []string{"votescast", "votesvalid", "votesinvalid", "crypt_key", "crypt_signature", "votes_raw", "votes_signature"} {
+		tx.set(fqid, field, nil)
+	}
+
+	var optionIDs []int
+	var globalOptionID int
+	json.Unmarshal(tx.get(fqid, "option_ids"), &optionIDs)
+	json.Unmarshal(tx.get(fqid, "global_option_id"), &globalOptionID)
+	for _, optionID := range append(optionIDs, globalOptionID) {
+		for _, field := range []string{"yes", "no", "abstain"} {
+			tx.set(fmt.Sprintf("option/%d", optionID), field, nil)
+		}
+	}
+
+	tx.s.mu.Lock()
+	delete(tx.s.votes, pollID)
+	delete(tx.s.closedPolls, pollID)
+	tx.s.mu.Unlock()
+
+	return nil, nil
+}
+
+// countVotes writes the results of a poll from the votes of the vote service.
+//
+// Votes of cryptographic polls are decrypted, if the server has a vote-decrypt
+// service. Otherwise they are only counted.
+func countVotes(tx *transaction, pollID int) error {
+	fqid := fmt.Sprintf("poll/%d", pollID)
+
+	var pollType, method string
+	var optionIDs []int
+	var globalOptionID int
+	json.Unmarshal(tx.get(fqid, "type"), &pollType)
+	json.Unmarshal(tx.get(fqid, "pollmethod"), &method)
+	json.Unmarshal(tx.get(fqid, "option_ids"), &optionIDs)
+	json.Unmarshal(tx.get(fqid, "global_option_id"), &globalOptionID)
+
+	votes := tx.s.closeVotes(pollID)
+
+	results := make(map[int]map[string]float64)
+	for _, optionID := range append(optionIDs, globalOptionID) {
+		results[optionID] = map[string]float64{"yes": 0, "no": 0, "abstain": 0}
+	}
+
+	var values []json.RawMessage
+	switch {
+	case pollType != "cryptographic":
+		for _, vote := range votes {
+			values = append(values, vote)
+		}
+
+	case tx.s.decrypt != nil:
+		decrypted, err := decryptVotes(tx, pollID, votes)
+		if err != nil {
+			return err
+		}
+		values = decrypted
+	}
+
+	invalid := 0
+	for _, value := range values {
+		if !addVote(results, value, method, globalOptionID) {
+			invalid++
+		}
+	}
+
+	tx.set(fqid, "votescast", decimalString(float64(len(votes))))
+	tx.set(fqid, "votesvalid", decimalString(float64(len(votes)-invalid)))
+	tx.set(fqid, "votesinvalid", decimalString(float64(invalid)))
+
+	for optionID, result := range results {
+		if optionID == 0 {
+			continue
+		}
+
+		optionFQID := fmt.Sprintf("option/%d", optionID)
+		for field, value := range result {
+			tx.set(optionFQID, field, decimalString(value))
+		}
+	}
+	return nil
+}
+
+// addVote adds one vote to the results. It returns false, if the vote is
+// invalid.
+func addVote(results map[int]map[string]float64, vote json.RawMessage, method string, globalOptionID int) bool {
+	answerField := map[string]string{"Y": "yes", "N": "no", "A": "abstain"}
+
+	var global string
+	if err := json.Unmarshal(vote, &global); err == nil {
+		if results[globalOptionID] != nil {
+			results[globalOptionID][answerField[global]]++
+		}
+		return true
+	}
+
+	switch method {
+	case "YN", "YNA":
+		var answers map[int]string
+		if err := json.Unmarshal(vote, &answers); err != nil {
+			return false
+		}
+		for optionID, answer := range answers {
+			if results[optionID] != nil {
+				results[optionID][answerField[answer]]++
+			}
+		}
+
+	default:
+		var amounts map[int]float64
+		if err := json.Unmarshal(vote, &amounts); err != nil {
+			return false
+		}
+		for optionID, amount := range amounts {
+			if results[optionID] != nil {
+				results[optionID]["yes"] += amount
+			}
+		}
+	}
+	return true
+}
+
+// decimal converts a number from an action to the decimal string, that
+// OpenSlides uses for results.
+func decimal(value json.RawMessage) string {
+	var f float64
+	json.Unmarshal(value, &f)
+	return decimalString(f)
+}
+
+func decimalString(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
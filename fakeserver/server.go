@@ -0,0 +1,358 @@
+// Package fakeserver implements a fake OpenSlides server.
+//
+// The server implements the parts of OpenSlides that are used by the
+// commands of this tool. It holds all data in memory and can be used for
+// tests or to try the commands without a real instance.
+package fakeserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/vote-decrypt/decrypt"
+)
+
+// Server is a fake OpenSlides server. It implements http.Handler.
+type Server struct {
+	mux *http.ServeMux
+
+	latency       time.Duration
+	errorRate     float64
+	actionWorker  bool
+	tokenLifetime time.Duration
+	decrypt       *decrypt.Decrypt
+
+	mu      sync.Mutex
+	data    map[string]map[string]json.RawMessage // fqid -> field -> value
+	lastIDs map[string]int                        // collection -> last used id
+	changed chan struct{}                         // closed and replaced on every change
+
+	users   map[string]fakeUser             // username -> user
+	tokens  map[string]token                // auth token -> token
+	cookies map[string]int                  // refresh cookie -> user id
+	votes   map[int]map[int]json.RawMessage // poll id -> user id -> vote
+
+	closedPolls map[int]bool // poll ids, whose votes were counted
+}
+
+type fakeUser struct {
+	id       int
+	password string
+}
+
+// Option is an optional argument for New.
+type Option func(*Server)
+
+// WithLatency delays the response of each request.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// WithErrorRate lets the given share of requests fail with status 500. rate
+// has to be between 0 and 1.
+func WithErrorRate(rate float64) Option {
+	return func(s *Server) {
+		s.errorRate = rate
+	}
+}
+
+// WithActionWorker lets the backend handle all actions with an action worker.
+//
+// The backend returns the status 202 and the result of the action has to be
+// fetched from the autoupdate service.
+func WithActionWorker() Option {
+	return func(s *Server) {
+		s.actionWorker = true
+	}
+}
+
+// WithTokenLifetime lets the auth tokens expire after the given duration.
+//
+// Clients have to use the refresh cookie to get a new token. Per default,
+// tokens do not expire.
+func WithTokenLifetime(d time.Duration) Option {
+	return func(s *Server) {
+		s.tokenLifetime = d
+	}
+}
+
+// New initializes a Server.
+//
+// The server contains the user "superadmin" with the password "superadmin",
+// a meeting with id 1, a started poll with id 1 and the method YNA and a
+// started election with id 2, the method Y and three options.
+func New(options ...Option) *Server {
+	s := Server{
+		mux:     http.NewServeMux(),
+		data:    make(map[string]map[string]json.RawMessage),
+		lastIDs: make(map[string]int),
+		changed: make(chan struct{}),
+		users:   make(map[string]fakeUser),
+		tokens:  make(map[string]token),
+		cookies: make(map[string]int),
+		votes:   make(map[int]map[int]json.RawMessage),
+
+		closedPolls: make(map[int]bool),
+	}
+
+	for _, o := range options {
+		o(&s)
+	}
+
+	s.initialData()
+
+	s.mux.HandleFunc("/system/auth/login", s.handleLogin)
+	s.mux.HandleFunc("/system/auth/who-am-i", s.handleWhoAmI)
+	s.mux.HandleFunc("/system/auth/who-am-i/", s.handleWhoAmI)
+	s.mux.HandleFunc("/system/autoupdate", s.handleAutoupdate)
+	s.mux.HandleFunc("/system/action/handle_request", s.handleAction)
+	s.mux.HandleFunc("/system/vote", s.handleVote)
+	s.mux.HandleFunc("/system/vote/voted", s.handleVoted)
+
+	return &s
+}
+
+// initialData creates the data that every server starts with.
+func (s *Server) initialData() {
+	tx := s.begin()
+	organization := map[string]any{"committee_ids": []int{1}, "active_meeting_ids": []int{1}}
+	if s.decrypt != nil {
+		organization["vote_decrypt_public_main_key"] = s.decrypt.PublicMainKey(context.Background())
+	}
+	tx.create("organization", organization)
+	tx.create("committee", map[string]any{"name": "Fake committee", "organization_id": 1, "meeting_ids": []int{1}})
+	tx.create("meeting", map[string]any{"name": "Fake meeting", "committee_id": 1, "group_ids": []int{1, 2}, "motion_state_ids": []int{1, 2}})
+	tx.create("group", map[string]any{"name": "Delegates", "external_id": "Delegates", "meeting_id": 1})
+	tx.create("group", map[string]any{"name": "Admin", "external_id": "Admin", "meeting_id": 1})
+	tx.create("motion_state", map[string]any{"name": "submitted", "meeting_id": 1, "next_state_ids": []int{2}})
+	tx.create("motion_state", map[string]any{"name": "accepted", "meeting_id": 1, "next_state_ids": []int{}})
+	tx.create("option", map[string]any{"poll_id": 1, "meeting_id": 1})
+	tx.create("poll", map[string]any{
+		"title":              "Fake poll",
+		"meeting_id":         1,
+		"type":               "named",
+		"pollmethod":         "YNA",
+		"state":              "started",
+		"option_ids":         []int{1},
+		"entitled_group_ids": []int{1},
+	})
+	for i := 0; i < 3; i++ {
+		tx.create("option", map[string]any{"poll_id": 2, "meeting_id": 1})
+	}
+	tx.create("poll", map[string]any{
+		"title":              "Fake election",
+		"meeting_id":         1,
+		"type":               "named",
+		"pollmethod":         "Y",
+		"state":              "started",
+		"option_ids":         []int{2, 3, 4},
+		"entitled_group_ids": []int{1},
+	})
+	tx.commit()
+
+	s.addUser("superadmin", "superadmin", nil)
+}
+
+// addUser creates a user. Has to be called without the lock.
+func (s *Server) addUser(username, password string, fields map[string]any) int {
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["username"] = username
+
+	tx := s.begin()
+	id := tx.create("user", fields)
+	tx.commit()
+
+	s.mu.Lock()
+	s.users[username] = fakeUser{id: id, password: password}
+	s.mu.Unlock()
+
+	return id
+}
+
+// ServeHTTP handles a request.
+//
+// It adds the configured latency and fails with the configured error rate.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.latency > 0 {
+		select {
+		case <-time.After(s.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if s.errorRate > 0 && mathrand.Float64() < s.errorRate {
+		http.Error(w, "injected error", http.StatusInternalServerError)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Get returns the value of a fqfield like "user/1/username". Returns nil, if
+// the value does not exist.
+func (s *Server) Get(fqfield string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(fqfield)
+}
+
+// get is like Get but has to be called with the lock.
+func (s *Server) get(fqfield string) json.RawMessage {
+	idx := strings.LastIndex(fqfield, "/")
+	if idx == -1 {
+		return nil
+	}
+	return s.data[fqfield[:idx]][fqfield[idx+1:]]
+}
+
+// waitForChange returns a channel that is closed on the next change of the
+// data.
+func (s *Server) waitForChange() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.changed
+}
+
+// transaction collects changes to the data and applies them at once.
+type transaction struct {
+	s      *Server
+	writes map[string]map[string]json.RawMessage // fqid -> field -> value. nil value means delete.
+}
+
+func (s *Server) begin() *transaction {
+	return &transaction{
+		s:      s,
+		writes: make(map[string]map[string]json.RawMessage),
+	}
+}
+
+// get returns the value of a fqfield including the changes of the
+// transaction.
+func (tx *transaction) get(fqid, field string) json.RawMessage {
+	if fields, ok := tx.writes[fqid]; ok {
+		if value, ok := fields[field]; ok {
+			return value
+		}
+	}
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+	return tx.s.data[fqid][field]
+}
+
+// exists returns true, if the object exists.
+func (tx *transaction) exists(fqid string) bool {
+	return tx.get(fqid, "id") != nil
+}
+
+// set sets a field. A nil value deletes the field.
+func (tx *transaction) set(fqid, field string, value any) {
+	if tx.writes[fqid] == nil {
+		tx.writes[fqid] = make(map[string]json.RawMessage)
+	}
+
+	if value == nil {
+		tx.writes[fqid][field] = nil
+		return
+	}
+
+	encoded, ok := value.(json.RawMessage)
+	if !ok {
+		var err error
+		encoded, err = json.Marshal(value)
+		if err != nil {
+			// All values are created by the server and can be encoded.
+			panic(fmt.Sprintf("encoding value %v: %v", value, err))
+		}
+	}
+	tx.writes[fqid][field] = encoded
+}
+
+// create creates a new object and returns its id.
+func (tx *transaction) create(collection string, fields map[string]any) int {
+	tx.s.mu.Lock()
+	tx.s.lastIDs[collection]++
+	id := tx.s.lastIDs[collection]
+	tx.s.mu.Unlock()
+
+	fqid := fmt.Sprintf("%s/%d", collection, id)
+	tx.set(fqid, "id", id)
+	for field, value := range fields {
+		tx.set(fqid, field, value)
+	}
+	return id
+}
+
+// delete removes all fields of an object.
+func (tx *transaction) delete(fqid string) {
+	tx.s.mu.Lock()
+	fields := make([]string, 0, len(tx.s.data[fqid]))
+	for field := range tx.s.data[fqid] {
+		fields = append(fields, field)
+	}
+	tx.s.mu.Unlock()
+
+	for field := range tx.writes[fqid] {
+		fields = append(fields, field)
+	}
+
+	for _, field := range fields {
+		tx.set(fqid, field, nil)
+	}
+}
+
+// commit applies the changes and informs all autoupdate connections.
+func (tx *transaction) commit() {
+	if len(tx.writes) == 0 {
+		return
+	}
+
+	s := tx.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for fqid, fields := range tx.writes {
+		for field, value := range fields {
+			if value == nil {
+				delete(s.data[fqid], field)
+				continue
+			}
+
+			if s.data[fqid] == nil {
+				s.data[fqid] = make(map[string]json.RawMessage)
+			}
+			s.data[fqid][field] = value
+		}
+
+		if len(s.data[fqid]) == 0 {
+			delete(s.data, fqid)
+		}
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// randomString returns a random string that can be used as token.
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("reading random data: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,279 @@
+package fakeserver_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/fakeserver"
+	"github.com/OpenSlides/openslides-performance/stats"
+	"github.com/OpenSlides/vote-decrypt/crypto"
+)
+
+func newClient(t *testing.T, srv *fakeserver.Server) *client.Client {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	c, err := client.New(client.Config{
+		Domain:   ts.URL,
+		Username: "superadmin",
+		Password: "superadmin",
+	})
+	if err != nil {
+		t.Fatalf("client.New(): %v", err)
+	}
+
+	if err := c.Login(context.Background()); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return c
+}
+
+func send(t *testing.T, c *client.Client, method, url, body string) string {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("sending request to %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(respBody)
+}
+
+func TestLogin(t *testing.T) {
+	c := newClient(t, fakeserver.New())
+
+	if c.UserID() != 1 {
+		t.Errorf("got user id %d, expected 1", c.UserID())
+	}
+}
+
+func TestTopicCreate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options []fakeserver.Option
+	}{
+		{"direct", nil},
+		{"action worker", []fakeserver.Option{fakeserver.WithActionWorker()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New(tt.options...)
+			c := newClient(t, srv)
+
+			got := send(t, c, "POST", "/system/action/handle_request", `[{"action":"topic.create","data":[{"meeting_id":1,"title":"hello"}]}]`)
+
+			var body struct {
+				Success bool `json:"success"`
+				Results [][]struct {
+					ID int `json:"id"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal([]byte(got), &body); err != nil {
+				t.Fatalf("decoding body `%s`: %v", got, err)
+			}
+
+			if !body.Success || body.Results[0][0].ID != 1 {
+				t.Errorf("got body `%s`, expected topic with id 1", got)
+			}
+
+			got = send(t, c, "GET", "/system/autoupdate?single=1", `[{"collection":"topic","ids":[1],"fields":{"title":null,"agenda_item_id":{"type":"relation","collection":"agenda_item","fields":{"closed":null}}}}]`)
+			expect := `{"agenda_item/1/closed":false,"topic/1/agenda_item_id":1,"topic/1/title":"hello"}`
+			if got != expect+"\n" {
+				t.Errorf("got autoupdate `%s`, expected `%s`", got, expect)
+			}
+		})
+	}
+}
+
+func TestAutoupdateStream(t *testing.T) {
+	srv := fakeserver.New()
+	c := newClient(t, srv)
+
+	req, err := http.NewRequest("GET", "/system/autoupdate?k=topic/1/title", nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	var data map[string]json.RawMessage
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("decoding first message: %v", err)
+	}
+
+	if len(data) != 0 {
+		t.Errorf("got first message %v, expected empty message", data)
+	}
+
+	send(t, c, "POST", "/system/action/handle_request", `[{"action":"topic.create","data":[{"meeting_id":1,"title":"hello"}]}]`)
+
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("decoding second message: %v", err)
+	}
+
+	if string(data["topic/1/title"]) != `"hello"` {
+		t.Errorf("got second message %v, expected the title", data)
+	}
+}
+
+func TestVote(t *testing.T) {
+	srv := fakeserver.New()
+	c := newClient(t, srv)
+
+	send(t, c, "POST", "/system/vote?id=1", `{"value":{"1":"Y"}}`)
+
+	req, err := http.NewRequest("POST", "/system/vote?id=1", strings.NewReader(`{"value":{"1":"Y"}}`))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	var errStatus client.HTTPStatusError
+	if _, err := c.Do(req); !errors.As(err, &errStatus) || errStatus.StatusCode != 400 {
+		t.Errorf("second vote returned %v, expected status 400", err)
+	}
+
+	if got := send(t, c, "GET", "/system/vote/voted?ids=1", ""); got != `{"1":true}`+"\n" {
+		t.Errorf("voted returned `%s`, expected poll 1 to be voted", got)
+	}
+
+	if votes := srv.Votes(1); len(votes) != 1 {
+		t.Errorf("got %d votes, expected 1", len(votes))
+	}
+}
+
+func TestVoteDelegation(t *testing.T) {
+	srv := fakeserver.New()
+	admin := newClient(t, srv)
+
+	// The users 2 and 3 get the meeting users 1 and 2.
+	send(t, admin, "POST", "/system/action/handle_request", `[{"action":"user.create","data":[
+		{"username":"delegate","default_password":"pass","meeting_id":1,"group_ids":[1]},
+		{"username":"principal","default_password":"pass","meeting_id":1,"group_ids":[1]}
+	]}]`)
+	send(t, admin, "POST", "/system/action/handle_request", `[{"action":"user.update","data":[
+		{"id":3,"meeting_id":1,"vote_delegated_to_id":1}
+	]}]`)
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	delegate, err := client.New(client.Config{Domain: ts.URL})
+	if err != nil {
+		t.Fatalf("client.New(): %v", err)
+	}
+	if err := delegate.LoginWithCredentials(context.Background(), "delegate", "pass"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	send(t, delegate, "POST", "/system/vote?id=1", `{"user_id":3,"value":{"1":"Y"}}`)
+	if votes := srv.Votes(1); votes[3] == nil {
+		t.Errorf("got votes %v, expected a vote for user 3", votes)
+	}
+
+	req, err := http.NewRequest("POST", "/system/vote?id=1", strings.NewReader(`{"user_id":1,"value":{"1":"Y"}}`))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	var errStatus client.HTTPStatusError
+	if _, err := delegate.Do(req); !errors.As(err, &errStatus) || errStatus.StatusCode != 403 {
+		t.Errorf("vote for a user without delegation returned %v, expected status 403", err)
+	}
+}
+
+func TestCryptographicPoll(t *testing.T) {
+	srv := fakeserver.New(fakeserver.WithDecrypt())
+	c := newClient(t, srv)
+
+	send(t, c, "POST", "/system/action/handle_request", `[{"action":"poll.create","data":[{
+		"meeting_id": 1,
+		"title": "Crypt poll",
+		"type": "cryptographic",
+		"pollmethod": "YNA",
+		"options": [{"text": "Option"}]
+	}]}]`)
+	send(t, c, "POST", "/system/action/handle_request", `[{"action":"poll.start","data":[{"id":3}]}]`)
+
+	var cryptKey []byte
+	if err := json.Unmarshal(srv.Get("poll/3/crypt_key"), &cryptKey); err != nil {
+		t.Fatalf("decoding crypt key: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt(rand.Reader, ecdh.X25519(), cryptKey, []byte(`{"5":"N"}`))
+	if err != nil {
+		t.Fatalf("encrypting vote: %v", err)
+	}
+
+	value, _ := json.Marshal(encrypted)
+	send(t, c, "POST", "/system/vote?id=3", fmt.Sprintf(`{"value":%s}`, value))
+	send(t, c, "POST", "/system/action/handle_request", `[{"action":"poll.stop","data":[{"id":3}]}]`)
+
+	if got := string(srv.Get("option/5/no")); got != `"1.000000"` {
+		t.Errorf("option 5 has %s no votes, expected 1", got)
+	}
+
+	var mainKey, signature []byte
+	var votesRaw string
+	json.Unmarshal(srv.Get("organization/1/vote_decrypt_public_main_key"), &mainKey)
+	json.Unmarshal(srv.Get("poll/3/votes_signature"), &signature)
+	json.Unmarshal(srv.Get("poll/3/votes_raw"), &votesRaw)
+	if !crypto.Verify(mainKey, []byte(votesRaw), signature) {
+		t.Errorf("signature of votes %s is invalid", votesRaw)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	srv := fakeserver.New(fakeserver.WithTokenLifetime(time.Millisecond))
+	collector := stats.New()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	c, err := client.New(client.Config{
+		Domain:   ts.URL,
+		Username: "superadmin",
+		Password: "superadmin",
+		Stats:    collector,
+	})
+	if err != nil {
+		t.Fatalf("client.New(): %v", err)
+	}
+
+	if err := c.Login(context.Background()); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	send(t, c, "POST", "/system/action/handle_request", `[{"action":"topic.create","data":[{"meeting_id":1,"title":"hello"}]}]`)
+
+	if got := collector.Histogram("token refresh").Count(); got != 1 {
+		t.Errorf("got %d refreshes, expected 1", got)
+	}
+}
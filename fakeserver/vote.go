@@ -0,0 +1,228 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// handleVote implements the vote route of the vote service.
+//
+// Each user can vote once on a started poll. With the field user_id, a user
+// votes for another user, that has delegated the vote to them. The votes are
+// only saved in memory.
+func (s *Server) handleVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := s.auth(w, r)
+	if !ok {
+		return
+	}
+
+	if userID == 0 {
+		writeVoteError(w, http.StatusUnauthorized, "not-allowed", "Anonymous can not vote")
+		return
+	}
+
+	pollID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeVoteError(w, http.StatusBadRequest, "invalid", "Invalid poll id")
+		return
+	}
+
+	var body struct {
+		UserID int             `json:"user_id"`
+		Value  json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Value) == 0 {
+		writeVoteError(w, http.StatusBadRequest, "invalid", "Invalid body")
+		return
+	}
+
+	// The poll can be stopped at the same time. The state is checked with the
+	// same lock as the vote is saved, so no vote is saved after the poll was
+	// counted.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state string
+	if err := json.Unmarshal(s.get(fmt.Sprintf("poll/%d/state", pollID)), &state); err != nil {
+		writeVoteError(w, http.StatusNotFound, "not-exist", fmt.Sprintf("Poll %d does not exist", pollID))
+		return
+	}
+
+	if state != "started" || s.closedPolls[pollID] {
+		writeVoteError(w, http.StatusBadRequest, "stopped", fmt.Sprintf("Poll %d is not started", pollID))
+		return
+	}
+
+	if body.UserID != 0 && body.UserID != userID {
+		if err := s.checkDelegation(pollID, userID, body.UserID); err != nil {
+			writeVoteError(w, http.StatusForbidden, "not-allowed", err.Error())
+			return
+		}
+		userID = body.UserID
+	}
+
+	if err := s.validateVote(pollID, body.Value); err != nil {
+		writeVoteError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	if s.votes[pollID] == nil {
+		s.votes[pollID] = make(map[int]json.RawMessage)
+	}
+
+	if _, ok := s.votes[pollID][userID]; ok {
+		writeVoteError(w, http.StatusBadRequest, "double-vote", "Not the first vote")
+		return
+	}
+
+	s.votes[pollID][userID] = body.Value
+}
+
+// checkDelegation returns an error, if the principal has not delegated the
+// vote in the meeting of the poll to the user. Has to be called with the lock.
+func (s *Server) checkDelegation(pollID, userID, principalID int) error {
+	var meetingID int
+	json.Unmarshal(s.get(fmt.Sprintf("poll/%d/meeting_id", pollID)), &meetingID)
+
+	get := func(fqid, field string) json.RawMessage {
+		return s.get(fqid + "/" + field)
+	}
+
+	principalMeetingUserID := findMeetingUser(get, principalID, meetingID)
+	if principalMeetingUserID == 0 {
+		return fmt.Errorf("user %d is not in meeting %d", principalID, meetingID)
+	}
+
+	var delegateID int
+	json.Unmarshal(s.get(fmt.Sprintf("meeting_user/%d/vote_delegated_to_id", principalMeetingUserID)), &delegateID)
+	if delegateID == 0 || delegateID != findMeetingUser(get, userID, meetingID) {
+		return fmt.Errorf("user %d can not vote for user %d", userID, principalID)
+	}
+	return nil
+}
+
+// validateVote checks the vote value like the vote service does.
+//
+// The value is a global answer like "A" or an object with option ids as
+// keys. For the methods YN and YNA, the values are answers. For other
+// methods, the values are amounts. Values of cryptographic polls are not
+// checked. Has to be called with the lock.
+func (s *Server) validateVote(pollID int, value json.RawMessage) error {
+	var pollType, method string
+	json.Unmarshal(s.get(fmt.Sprintf("poll/%d/type", pollID)), &pollType)
+	json.Unmarshal(s.get(fmt.Sprintf("poll/%d/pollmethod", pollID)), &method)
+
+	if pollType == "cryptographic" {
+		return nil
+	}
+
+	var global string
+	if err := json.Unmarshal(value, &global); err == nil {
+		if global != "Y" && global != "N" && global != "A" {
+			return fmt.Errorf("invalid global answer %q", global)
+		}
+		return nil
+	}
+
+	var options map[int]json.RawMessage
+	if err := json.Unmarshal(value, &options); err != nil {
+		return fmt.Errorf("invalid vote value")
+	}
+
+	var optionIDs []int
+	json.Unmarshal(s.get(fmt.Sprintf("poll/%d/option_ids", pollID)), &optionIDs)
+	isOption := make(map[int]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		isOption[id] = true
+	}
+
+	for optionID, rawAnswer := range options {
+		if !isOption[optionID] {
+			return fmt.Errorf("option %d is not part of the poll", optionID)
+		}
+
+		switch method {
+		case "YN", "YNA":
+			var answer string
+			if err := json.Unmarshal(rawAnswer, &answer); err != nil || len(answer) != 1 || !strings.Contains(method, answer) {
+				return fmt.Errorf("invalid answer for option %d", optionID)
+			}
+
+		default:
+			var amount int
+			if err := json.Unmarshal(rawAnswer, &amount); err != nil || amount < 0 {
+				return fmt.Errorf("invalid amount for option %d", optionID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// handleVoted tells, if the request user has already voted on the polls
+// given by the query argument "ids".
+func (s *Server) handleVoted(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.auth(w, r)
+	if !ok {
+		return
+	}
+
+	if userID == 0 {
+		writeVoteError(w, http.StatusUnauthorized, "not-allowed", "Anonymous can not vote")
+		return
+	}
+
+	voted := make(map[int]bool)
+	for _, rawID := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		pollID, err := strconv.Atoi(rawID)
+		if err != nil {
+			writeVoteError(w, http.StatusBadRequest, "invalid", fmt.Sprintf("Invalid poll id %q", rawID))
+			return
+		}
+
+		s.mu.Lock()
+		_, voted[pollID] = s.votes[pollID][userID]
+		s.mu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voted)
+}
+
+// Votes returns the votes of a poll by user id.
+func (s *Server) Votes(pollID int) map[int]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	votes := make(map[int]json.RawMessage, len(s.votes[pollID]))
+	for userID, vote := range s.votes[pollID] {
+		votes[userID] = vote
+	}
+	return votes
+}
+
+// closeVotes stops the vote service for a poll and returns its votes. After
+// it was called, no vote is accepted until the poll is reset.
+func (s *Server) closeVotes(pollID int) map[int]json.RawMessage {
+	s.mu.Lock()
+	s.closedPolls[pollID] = true
+	s.mu.Unlock()
+
+	return s.Votes(pollID)
+}
+
+// writeVoteError writes an error like the vote service does.
+func writeVoteError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoded, _ := json.Marshal(message)
+	fmt.Fprintf(w, `{"error":"%s","message":%s}`, errType, encoded)
+}
@@ -12,6 +12,7 @@ require (
 	github.com/quic-go/quic-go v0.41.0
 	github.com/vbauerster/mpb/v7 v7.5.3
 	golang.org/x/sync v0.6.0
+	gopkg.in/yaml.v3 v3.0.1
 	nhooyr.io/websocket v1.8.10
 )
 
@@ -22,6 +23,7 @@ require (
 	github.com/containerd/console v1.0.4 // indirect
 	github.com/go-task/slim-sprig v0.0.0-20230315185526-52ccab3ef572 // indirect
 	github.com/google/pprof v0.0.0-20240207164012-fb44976bdcd5 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-localereader v0.0.1 // indirect
@@ -5,17 +5,26 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/OpenSlides/openslides-performance/backendaction"
 	"github.com/OpenSlides/openslides-performance/brokenproxy"
 	"github.com/OpenSlides/openslides-performance/browser"
 	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/compare"
 	"github.com/OpenSlides/openslides-performance/connect"
 	"github.com/OpenSlides/openslides-performance/createusers"
+	"github.com/OpenSlides/openslides-performance/fakeserver"
 	"github.com/OpenSlides/openslides-performance/request"
+	"github.com/OpenSlides/openslides-performance/scenario"
 	"github.com/OpenSlides/openslides-performance/slow"
+	"github.com/OpenSlides/openslides-performance/stats"
 	"github.com/OpenSlides/openslides-performance/vote"
 	"github.com/OpenSlides/openslides-performance/work"
 	"github.com/alecthomas/kong"
@@ -26,9 +35,45 @@ func main() {
 	defer cancel()
 
 	cliCtx := kong.Parse(&cli, kong.UsageOnError(), kong.Configuration(kong.JSON, "config.json"))
+	cli.Config.Stats = stats.New()
+
+	ctx, cancelDuration := cli.Config.WithDuration(ctx)
+	defer cancelDuration()
+
 	cliCtx.BindTo(ctx, (*context.Context)(nil))
 	cliCtx.Bind(cli.Config)
-	if err := cliCtx.Run(); err != nil {
+
+	if cli.Config.MetricsAddr != "" {
+		go serveMetrics(ctx, cli.Config.MetricsAddr, cli.Config.Stats)
+	}
+
+	start := time.Now()
+	err := cliCtx.Run()
+	end := time.Now()
+
+	// The summary is written to stderr like the log messages, so it does not
+	// mix with the output of commands like "request".
+	cli.Config.Stats.Print(os.Stderr)
+
+	if cli.Config.Report != "" {
+		result := stats.Result{
+			Command: commandName(cliCtx),
+			Options: commandOptions(cliCtx),
+			Start:   start,
+			End:     end,
+			Metrics: cli.Config.Stats.Metrics(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		if err := result.WriteFile(cli.Config.Report); err != nil {
+			fmt.Printf("Error: writing report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -53,15 +98,91 @@ func interruptContext() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
+// serveMetrics starts a http server that exposes the metrics from the
+// collector.
+//
+// Blocks until the context is done.
+func serveMetrics(ctx context.Context, addr string, collector *stats.Collector) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", collector)
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Printf("Metrics server failed: %v", err)
+	}
+}
+
+// commandName returns the name of the selected command without positional
+// arguments.
+func commandName(cliCtx *kong.Context) string {
+	var names []string
+	for _, trace := range cliCtx.Path {
+		if trace.Command != nil {
+			names = append(names, trace.Command.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// commandOptions returns the values of all flags and arguments of the
+// selected command.
+//
+// Passwords are not returned.
+func commandOptions(cliCtx *kong.Context) map[string]string {
+	values := cliCtx.Flags()
+	if node := cliCtx.Selected(); node != nil {
+		for _, positional := range node.Positional {
+			values = append(values, &kong.Flag{Value: positional})
+		}
+	}
+
+	options := make(map[string]string)
+	for _, value := range values {
+		if value.Name == "help" || strings.Contains(value.Name, "password") || !value.Target.IsValid() {
+			continue
+		}
+
+		options[value.Name] = formatOption(value.Target)
+	}
+	return options
+}
+
+func formatOption(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case *os.File:
+		return value.Name()
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
 var cli struct {
 	client.Config
 
 	BackendAction backendaction.Options `cmd:"" help:"Calls a backend action multiple times."`
 	BrokenProxy   brokenproxy.Options   `cmd:"" help:"Starts a broken proxy."`
 	Browser       browser.Options       `cmd:"" help:"Simulates a browser."`
+	Compare       compare.Options       `cmd:"" help:"Compares two result files."`
 	Connect       connect.Options       `cmd:"" help:"Opens many connections to autoupdate and keeps them open."`
 	CreateUsers   createusers.Options   `cmd:"" help:"Create many users."`
+	FakeServer    fakeserver.Options    `cmd:"" help:"Starts a fake OpenSlides server."`
 	Request       request.Options       `cmd:"" help:"Sends a logged-in request to OpenSlides."`
+	Scenario      scenario.Options      `cmd:"" help:"Runs many commands in phases."`
 	Slow          slow.Options          `cmd:"" help:"Sends many slow requests."`
 	Vote          vote.Options          `cmd:"" help:"Sends many votes from different users."`
 	Work          work.Options          `cmd:"" help:"Generates background work."`
@@ -0,0 +1,13 @@
+package rate
+
+import "time"
+
+// Options configures the arrival rate of a command.
+//
+// It is embedded in the options of the commands that support an arrival rate.
+type Options struct {
+	Rate     string        `help:"Start requests with this arrival rate, e.g. 50/s, 600/m or 1/100ms. Default is as fast as the server answers." group:"Arrival rate"`
+	Schedule string        `help:"Schedule of the arrivals. constant: fixed intervals. linear: the rate grows from --rate to --rate-end in --rate-ramp. poisson: random intervals with the average of --rate." default:"constant" enum:"constant,linear,poisson" group:"Arrival rate"`
+	RateEnd  string        `help:"Rate at the end of the linear schedule." group:"Arrival rate"`
+	RateRamp time.Duration `help:"Duration in which the linear schedule grows to --rate-end." default:"1m" group:"Arrival rate"`
+}
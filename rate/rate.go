@@ -0,0 +1,140 @@
+// Package rate starts work with a fixed arrival rate.
+//
+// Other then a closed loop, where the next request is sent when the last one
+// is answered, the work is started independently of the answers. This is
+// called an open model. It shows how the latency of the server changes with
+// the load.
+package rate
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Enabled returns true, if an arrival rate is set.
+func (o Options) Enabled() bool {
+	return o.Rate != ""
+}
+
+// Run calls fn in a new goroutine for each arrival.
+//
+// i is the number of the arrival starting at 0. If n is greater than 0, Run
+// stops after n arrivals. Otherwise it stops when the context is done. In
+// both cases, Run blocks until all calls of fn have returned.
+func (o Options) Run(ctx context.Context, n int, fn func(ctx context.Context, i int)) error {
+	next, err := o.schedule()
+	if err != nil {
+		return fmt.Errorf("parsing rate: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	start := time.Now()
+	arrival := start
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i := 0; n <= 0 || i < n; i++ {
+		// The arrivals are calculated from the start time and not from the
+		// last call of fn. If the tool is too slow, the next calls are made
+		// immediately.
+		timer.Reset(time.Until(arrival))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn(ctx, i)
+		}(i)
+
+		arrival = arrival.Add(next(arrival.Sub(start)))
+	}
+	return nil
+}
+
+// schedule returns a function that returns the interval to the next arrival.
+// elapsed is the time since the start.
+func (o Options) schedule() (func(elapsed time.Duration) time.Duration, error) {
+	rate, err := ParseRate(o.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate %q: %w", o.Rate, err)
+	}
+
+	switch o.Schedule {
+	case "", "constant":
+		return func(time.Duration) time.Duration {
+			return interval(rate)
+		}, nil
+
+	case "poisson":
+		return func(time.Duration) time.Duration {
+			return time.Duration(rand.ExpFloat64() * float64(interval(rate)))
+		}, nil
+
+	case "linear":
+		rateEnd, err := ParseRate(o.RateEnd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate end %q: %w", o.RateEnd, err)
+		}
+
+		return func(elapsed time.Duration) time.Duration {
+			if elapsed >= o.RateRamp || o.RateRamp <= 0 {
+				return interval(rateEnd)
+			}
+
+			progress := float64(elapsed) / float64(o.RateRamp)
+			return interval(rate + (rateEnd-rate)*progress)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown schedule %q", o.Schedule)
+	}
+}
+
+// interval returns the time between two arrivals for a rate per second.
+func interval(perSecond float64) time.Duration {
+	return time.Duration(float64(time.Second) / perSecond)
+}
+
+// ParseRate parses a rate like 50/s, 600/m or 1/100ms. It returns the rate
+// per second. A rate without a unit is per second.
+func ParseRate(s string) (float64, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(s), "/")
+	value, err := strconv.ParseFloat(count, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count: %w", err)
+	}
+
+	if value <= 0 {
+		return 0, fmt.Errorf("rate has to be positive")
+	}
+
+	if !found {
+		return value, nil
+	}
+
+	if unit != "" && (unit[0] < '0' || unit[0] > '9') {
+		unit = "1" + unit
+	}
+
+	per, err := time.ParseDuration(unit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unit: %w", err)
+	}
+
+	if per <= 0 {
+		return 0, fmt.Errorf("unit has to be positive")
+	}
+
+	return value / per.Seconds(), nil
+}
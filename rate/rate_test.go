@@ -0,0 +1,59 @@
+package rate_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/rate"
+)
+
+func TestParseRate(t *testing.T) {
+	for _, tt := range []struct {
+		rate   string
+		expect float64
+	}{
+		{"50", 50},
+		{"50/s", 50},
+		{"600/m", 10},
+		{"1/100ms", 10},
+		{"2/5s", 0.4},
+	} {
+		got, err := rate.ParseRate(tt.rate)
+		if err != nil {
+			t.Errorf("ParseRate(%q): %v", tt.rate, err)
+			continue
+		}
+
+		if got != tt.expect {
+			t.Errorf("ParseRate(%q) returned %f, expected %f", tt.rate, got, tt.expect)
+		}
+	}
+
+	for _, invalid := range []string{"", "fast", "-1/s", "1/fortnight"} {
+		if _, err := rate.ParseRate(invalid); err == nil {
+			t.Errorf("ParseRate(%q) did not return an error", invalid)
+		}
+	}
+}
+
+func TestRunStopsAfterN(t *testing.T) {
+	o := rate.Options{Rate: "1000/s", Schedule: "constant"}
+
+	var calls atomic.Int32
+	start := time.Now()
+	if err := o.Run(context.Background(), 50, func(ctx context.Context, i int) {
+		calls.Add(1)
+	}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if got := calls.Load(); got != 50 {
+		t.Errorf("fn was called %d times, expected 50", got)
+	}
+
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("Run took %v, expected about 50ms", elapsed)
+	}
+}
@@ -3,6 +3,8 @@ package request
 import (
 	"net/url"
 	"os"
+
+	"github.com/OpenSlides/openslides-performance/rate"
 )
 
 // Options is the meta information for the cli.
@@ -12,9 +14,14 @@ type Options struct {
 	Body            []string `help:"HTTP Post body." short:"b" sep:"\n"`
 	BodyFile        *os.File `help:"Request Body from a file. Use - for stdin"`
 	NoBackendWorker bool     `help:"Disable automatic handeling of backend workers"`
+
+	Arrival rate.Options `embed:""`
 }
 
 // Help returns the help message
 func (o Options) Help() string {
-	return `Before the request, a login request is send and the credentials are used for the actual request.`
+	return `Before the request, a login request is send and the credentials are used for the actual request.
+
+With --rate, the request is sent again and again with the given arrival rate
+until the command is stopped. In this case, the responses are discarded.`
 }
@@ -6,11 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"os"
-	"strings"
 
 	"github.com/OpenSlides/openslides-performance/client"
 )
@@ -36,12 +36,12 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 	}
 
 	method := "GET"
-	var body io.Reader
+	var body []byte
 
 	boundary := ""
 	if len(o.Body) != 0 {
 		method = "POST"
-		body = strings.NewReader(o.Body[0])
+		body = []byte(o.Body[0])
 		if len(o.Body) > 1 {
 			buf := new(bytes.Buffer)
 			mp := multipart.NewWriter(buf)
@@ -55,28 +55,57 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 				}
 			}
 			mp.Close()
-			body = buf
+			body = buf.Bytes()
 			boundary = mp.Boundary()
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, o.URL.String(), body)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+	do := c.Do
+	if o.NoBackendWorker {
+		do = c.DoRaw
 	}
 
-	if boundary != "" {
-		req.Header.Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%s", boundary))
+	send := func(ctx context.Context) (*http.Response, error) {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, o.URL.String(), reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+
+		if boundary != "" {
+			req.Header.Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%s", boundary))
+		}
+
+		resp, err := do(req)
+		if err != nil {
+			return nil, fmt.Errorf("sending request: %w", err)
+		}
+		return resp, nil
 	}
 
-	do := c.Do
-	if o.NoBackendWorker {
-		do = c.DoRaw
+	if o.Arrival.Enabled() {
+		// The responses are not written to stdout, since there are too many of
+		// them.
+		return o.Arrival.Run(ctx, 0, func(ctx context.Context, i int) {
+			resp, err := send(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Printf("Request %d: %v", i+1, err)
+				}
+				return
+			}
+			defer resp.Body.Close()
+			io.Copy(io.Discard, resp.Body)
+		})
 	}
 
-	resp, err := do(req)
+	resp, err := send(ctx)
 	if err != nil {
-		return fmt.Errorf("sending request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
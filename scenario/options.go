@@ -0,0 +1,60 @@
+package scenario
+
+import "os"
+
+// Options is the meta information for the cli.
+type Options struct {
+	File *os.File `arg:"" help:"Scenario file in json or yaml format. Use - for stdin in json format."`
+}
+
+// Help returns the help message
+func (o Options) Help() string {
+	return `A scenario consists of phases that run one after another. Each phase runs
+one or more commands at the same time. A command is written as a list of
+arguments like on the command line. Global arguments like --domain are taken
+from the scenario command and can not be set per command.
+
+A phase with a duration is stopped after that time. A phase without a
+duration runs until all of its commands are finished.
+
+All commands share the logins of their users, so each user is only logged in
+once. After each phase, the statistics of the phase are printed. At the end,
+the statistics of all phases are combined.
+
+Supported commands: backend-action, connect, create-users, request, slow,
+vote, work.
+
+The file is read as yaml, if it has the extension .yaml or .yml, and as json
+otherwise.
+
+Example file:
+
+{
+  "phases": [
+    {
+      "name": "setup",
+      "commands": [["create-users", "-n", "100", "-m", "1"]]
+    },
+    {
+      "name": "steady load",
+      "duration": "5m",
+      "commands": [
+        ["connect", "-n", "100", "-m", "1"],
+        ["work", "-n", "5"]
+      ]
+    }
+  ]
+}
+
+The same file as yaml:
+
+phases:
+  - name: setup
+    commands:
+      - [create-users, -n, "100", -m, "1"]
+  - name: steady load
+    duration: 5m
+    commands:
+      - [connect, -n, "100", -m, "1"]
+      - [work, -n, "5"]`
+}
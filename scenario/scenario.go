@@ -0,0 +1,178 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/backendaction"
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/connect"
+	"github.com/OpenSlides/openslides-performance/createusers"
+	"github.com/OpenSlides/openslides-performance/request"
+	"github.com/OpenSlides/openslides-performance/slow"
+	"github.com/OpenSlides/openslides-performance/vote"
+	"github.com/OpenSlides/openslides-performance/work"
+	"github.com/alecthomas/kong"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// Run runs the command.
+func (o Options) Run(ctx context.Context, cfg client.Config) error {
+	file, err := decodeFile(o.File)
+	if err != nil {
+		return fmt.Errorf("decoding scenario file: %w", err)
+	}
+
+	// Parse all phases before the first one is started, so a typo in the
+	// last phase does not stop the scenario in the middle.
+	phases := make([]phase, len(file.Phases))
+	for i, p := range file.Phases {
+		parsed, err := parsePhase(p)
+		if err != nil {
+			return fmt.Errorf("phase %d: %w", i+1, err)
+		}
+		phases[i] = parsed
+	}
+
+	if cfg.Sessions == nil {
+		cfg.Sessions = client.NewSessions()
+	}
+
+	for _, p := range phases {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := p.run(ctx, cfg); err != nil {
+			return fmt.Errorf("phase %s: %w", p.name, err)
+		}
+	}
+
+	return nil
+}
+
+// scenarioFile is the content of the scenario file.
+type scenarioFile struct {
+	Phases []phaseFile `json:"phases" yaml:"phases"`
+}
+
+type phaseFile struct {
+	Name     string     `json:"name" yaml:"name"`
+	Duration string     `json:"duration" yaml:"duration"`
+	Commands [][]string `json:"commands" yaml:"commands"`
+}
+
+// decodeFile decodes the scenario file. Files with the extension .yaml or .yml
+// are decoded as yaml, all other files, including stdin, as json.
+func decodeFile(f *os.File) (scenarioFile, error) {
+	var file scenarioFile
+	switch strings.ToLower(filepath.Ext(f.Name())) {
+	case ".yaml", ".yml":
+		if err := yaml.NewDecoder(f).Decode(&file); err != nil {
+			return scenarioFile{}, fmt.Errorf("decoding yaml: %w", err)
+		}
+
+	default:
+		if err := json.NewDecoder(f).Decode(&file); err != nil {
+			return scenarioFile{}, fmt.Errorf("decoding json: %w", err)
+		}
+	}
+	return file, nil
+}
+
+// commands are the commands that can be used in a scenario.
+type commands struct {
+	BackendAction backendaction.Options `cmd:""`
+	Connect       connect.Options       `cmd:""`
+	CreateUsers   createusers.Options   `cmd:""`
+	Request       request.Options       `cmd:""`
+	Slow          slow.Options          `cmd:""`
+	Vote          vote.Options          `cmd:""`
+	Work          work.Options          `cmd:""`
+}
+
+type phase struct {
+	name     string
+	duration time.Duration
+	commands []*kong.Context
+}
+
+func parsePhase(p phaseFile) (phase, error) {
+	parsed := phase{name: p.Name}
+
+	if p.Duration != "" {
+		d, err := time.ParseDuration(p.Duration)
+		if err != nil {
+			return phase{}, fmt.Errorf("parsing duration: %w", err)
+		}
+		parsed.duration = d
+	}
+
+	if len(p.Commands) == 0 {
+		return phase{}, fmt.Errorf("phase has no commands")
+	}
+
+	for _, args := range p.Commands {
+		// Each command needs its own target, so the same command can be used
+		// multiple times with different options.
+		var target commands
+		parser, err := kong.New(&target, kong.Name("scenario"))
+		if err != nil {
+			return phase{}, fmt.Errorf("creating parser: %w", err)
+		}
+
+		kongCtx, err := parser.Parse(args)
+		if err != nil {
+			return phase{}, fmt.Errorf("parsing command `%s`: %w", strings.Join(args, " "), err)
+		}
+
+		parsed.commands = append(parsed.commands, kongCtx)
+	}
+
+	return parsed, nil
+}
+
+// run runs all commands of the phase and prints the statistics of the phase.
+//
+// The statistics are also added to the stats collector of cfg.
+func (p phase) run(ctx context.Context, cfg client.Config) error {
+	log.Printf("Start phase %s", p.name)
+	start := time.Now()
+
+	phaseStats := cfg.Stats.Sub()
+	phaseCfg := cfg
+	phaseCfg.Stats = phaseStats
+	phaseCfg.Duration = p.duration
+
+	ctx, cancel := phaseCfg.WithDuration(ctx)
+	defer cancel()
+
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, kongCtx := range p.commands {
+		eg.Go(func() error {
+			kongCtx.BindTo(ctx, (*context.Context)(nil))
+			kongCtx.Bind(phaseCfg)
+			if err := kongCtx.Run(); err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+					return nil
+				}
+				return fmt.Errorf("command %s: %w", kongCtx.Command(), err)
+			}
+			return nil
+		})
+	}
+	err := eg.Wait()
+
+	log.Printf("Phase %s finished after %v", p.name, time.Since(start).Round(time.Millisecond))
+	phaseStats.Print(os.Stderr)
+
+	return err
+}
@@ -19,10 +19,17 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		return fmt.Errorf("creating client: %w", err)
 	}
 
+	ramp := cfg.Ramp(o.Amount)
 	eg, ctx := errgroup.WithContext(ctx)
 
 	for i := 0; i < o.Amount; i++ {
 		eg.Go(func() error {
+			ctx, cancel, err := ramp.Wait(ctx, i)
+			if err != nil {
+				return nil
+			}
+			defer cancel()
+
 			for ctx.Err() == nil {
 				req, err := http.NewRequestWithContext(ctx, "POST", o.URL.String(), slowRandromReader{})
 				if err != nil {
@@ -31,6 +38,10 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 
 				resp, err := c.Do(req)
 				if err != nil {
+					if ctx.Err() != nil {
+						// The worker was stopped.
+						return nil
+					}
 					return fmt.Errorf("sending request: %w", err)
 				}
 				defer resp.Body.Close()
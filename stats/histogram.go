@@ -0,0 +1,198 @@
+package stats
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBucketBits is the number of significant bits of a value that are kept in
+// the histogram. Seven bits result in a relative error of less then 1.6%.
+const subBucketBits = 7
+
+const (
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram records durations in log-linear buckets like a HDR histogram.
+//
+// The values are saved with microsecond resolution. Small values are saved
+// exactly. Bigger values are saved with a relative error of less then 1.6%.
+//
+// A Histogram is not safe for concurrent use.
+type Histogram struct {
+	buckets []int64
+	count   int64
+	sum     int64
+	min     int64
+	max     int64
+}
+
+// Add records one duration.
+func (h *Histogram) Add(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+
+	idx := bucketIndex(v)
+	if idx >= len(h.buckets) {
+		buckets := make([]int64, idx+1)
+		copy(buckets, h.buckets)
+		h.buckets = buckets
+	}
+	h.buckets[idx]++
+
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+// Merge adds all values from other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+
+	if len(other.buckets) > len(h.buckets) {
+		buckets := make([]int64, len(other.buckets))
+		copy(buckets, h.buckets)
+		h.buckets = buckets
+	}
+
+	for i, c := range other.buckets {
+		h.buckets[i] += c
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Since returns the values, that were added to h after before was copied from
+// it.
+//
+// The smallest and biggest value of the result are only known with the
+// precision of the buckets.
+func (h Histogram) Since(before Histogram) Histogram {
+	var diff Histogram
+	diff.count = h.count - before.count
+	diff.sum = h.sum - before.sum
+	if diff.count <= 0 {
+		return Histogram{}
+	}
+
+	diff.buckets = make([]int64, len(h.buckets))
+	first, last := -1, 0
+	for i, c := range h.buckets {
+		if i < len(before.buckets) {
+			c -= before.buckets[i]
+		}
+		diff.buckets[i] = c
+
+		if c > 0 {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	diff.min = h.min
+	if first > 0 {
+		diff.min = max(h.min, bucketUpperBound(first-1)+1)
+	}
+	diff.max = min(h.max, bucketUpperBound(last))
+	return diff
+}
+
+// Count returns the number of recorded values.
+func (h Histogram) Count() int64 {
+	return h.count
+}
+
+// Min returns the smallest recorded value.
+func (h Histogram) Min() time.Duration {
+	return time.Duration(h.min) * time.Microsecond
+}
+
+// Max returns the biggest recorded value.
+func (h Histogram) Max() time.Duration {
+	return time.Duration(h.max) * time.Microsecond
+}
+
+// Sum returns the sum of all recorded values.
+func (h Histogram) Sum() time.Duration {
+	return time.Duration(h.sum) * time.Microsecond
+}
+
+// Mean returns the average of all recorded values.
+func (h Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/h.count) * time.Microsecond
+}
+
+// Percentile returns the value below which p percent of the recorded values
+// are. p has to be between 0 and 100.
+func (h Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, c := range h.buckets {
+		seen += c
+		if seen >= rank {
+			v := bucketUpperBound(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// bucketIndex returns the index of the bucket for the value v.
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	mantissa := v >> shift
+	return subBucketCount + (shift-1)*subBucketHalf + int(mantissa-subBucketHalf)
+}
+
+// bucketUpperBound returns the biggest value that is saved in the bucket with
+// the given index.
+func bucketUpperBound(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+
+	shift := (idx-subBucketCount)/subBucketHalf + 1
+	mantissa := int64((idx-subBucketCount)%subBucketHalf + subBucketHalf)
+	return (mantissa+1)<<shift - 1
+}
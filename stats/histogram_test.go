@@ -0,0 +1,100 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	var h stats.Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	for _, tt := range []struct {
+		percentile float64
+		expect     time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	} {
+		got := h.Percentile(tt.percentile)
+		diff := float64(got-tt.expect) / float64(tt.expect)
+		if diff < 0 || diff > 0.016 {
+			t.Errorf("p%v is %v, expected %v", tt.percentile, got, tt.expect)
+		}
+	}
+
+	if h.Count() != 1000 {
+		t.Errorf("count is %d, expected 1000", h.Count())
+	}
+
+	if h.Max() != time.Second {
+		t.Errorf("max is %v, expected 1s", h.Max())
+	}
+}
+
+func TestHistogramSmallValuesAreExact(t *testing.T) {
+	var h stats.Histogram
+	for i := 0; i < 100; i++ {
+		h.Add(time.Duration(i) * time.Microsecond)
+	}
+
+	if got := h.Percentile(50); got != 49*time.Microsecond {
+		t.Errorf("p50 is %v, expected 49µs", got)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var h1, h2 stats.Histogram
+	h1.Add(time.Millisecond)
+	h2.Add(time.Second)
+
+	h1.Merge(&h2)
+
+	if h1.Count() != 2 {
+		t.Errorf("count is %d, expected 2", h1.Count())
+	}
+
+	if h1.Min() != time.Millisecond || h1.Max() != time.Second {
+		t.Errorf("min/max is %v/%v, expected 1ms/1s", h1.Min(), h1.Max())
+	}
+}
+
+func TestHistogramSince(t *testing.T) {
+	var h stats.Histogram
+	for i := 0; i < 10; i++ {
+		h.Add(time.Second)
+	}
+
+	var before stats.Histogram
+	before.Merge(&h)
+
+	for i := 0; i < 10; i++ {
+		h.Add(10 * time.Millisecond)
+	}
+
+	diff := h.Since(before)
+
+	if diff.Count() != 10 {
+		t.Errorf("count is %d, expected 10", diff.Count())
+	}
+
+	if diff.Mean() != 10*time.Millisecond {
+		t.Errorf("mean is %v, expected 10ms", diff.Mean())
+	}
+
+	for _, d := range []time.Duration{diff.Min(), diff.Percentile(50), diff.Max()} {
+		if d < 9800*time.Microsecond || d > 10200*time.Microsecond {
+			t.Errorf("got %v, expected about 10ms", d)
+		}
+	}
+
+	if empty := h.Since(h); empty.Count() != 0 || empty.Max() != 0 {
+		t.Errorf("histogram since itself has count %d and max %v, expected 0", empty.Count(), empty.Max())
+	}
+}
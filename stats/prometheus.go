@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusPrefix is the prefix of all metric names.
+const prometheusPrefix = "openslides_performance_"
+
+// series is a counter or gauge with labels.
+type series struct {
+	name   string
+	labels string
+	gauge  bool
+	value  float64
+}
+
+// Inc increases the counter with the name and labels by one.
+//
+// labels are pairs of label names and values.
+func (c *Collector) Inc(name string, labels ...string) {
+	c.addSeries(name, false, 1, labels)
+}
+
+// AddGauge adds delta to the gauge with the name and labels.
+//
+// labels are pairs of label names and values.
+func (c *Collector) AddGauge(name string, delta float64, labels ...string) {
+	c.addSeries(name, true, delta, labels)
+}
+
+func (c *Collector) addSeries(name string, gauge bool, delta float64, labels []string) {
+	if c == nil {
+		return
+	}
+	c.parent.addSeries(name, gauge, delta, labels)
+
+	encoded := encodeLabels(labels...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := name + encoded
+	s, ok := c.series[key]
+	if !ok {
+		s = &series{name: name, labels: encoded, gauge: gauge}
+		c.series[key] = s
+	}
+	s.value += delta
+}
+
+// encodeLabels returns the labels in the prometheus format like
+// {name="value"}.
+func encodeLabels(labels ...string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", labels[i], strconv.Quote(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// ServeHTTP writes all values in the prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WritePrometheus(w)
+}
+
+// WritePrometheus writes all values in the prometheus text format.
+//
+// The latencies are written as summary with the name
+// request_duration_seconds and the errors as the counter
+// request_errors_total.
+func (c *Collector) WritePrometheus(w io.Writer) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.metrics))
+	for name := range c.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	durationName := prometheusPrefix + "request_duration_seconds"
+	fmt.Fprintf(w, "# TYPE %s summary\n", durationName)
+	for _, name := range names {
+		h := c.metrics[name].histogram
+		for _, q := range []float64{0.5, 0.9, 0.95, 0.99} {
+			labels := encodeLabels("name", name, "quantile", strconv.FormatFloat(q, 'f', -1, 64))
+			fmt.Fprintf(w, "%s%s %g\n", durationName, labels, h.Percentile(q*100).Seconds())
+		}
+		labels := encodeLabels("name", name)
+		fmt.Fprintf(w, "%s_sum%s %g\n", durationName, labels, h.Sum().Seconds())
+		fmt.Fprintf(w, "%s_count%s %d\n", durationName, labels, h.Count())
+	}
+
+	errorsName := prometheusPrefix + "request_errors_total"
+	fmt.Fprintf(w, "# TYPE %s counter\n", errorsName)
+	for _, name := range names {
+		errors := c.metrics[name].errors
+		kinds := make([]string, 0, len(errors))
+		for kind := range errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			fmt.Fprintf(w, "%s%s %d\n", errorsName, encodeLabels("name", name, "kind", kind), errors[kind])
+		}
+	}
+
+	all := make([]*series, 0, len(c.series))
+	for _, s := range c.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		return all[i].labels < all[j].labels
+	})
+
+	lastName := ""
+	for _, s := range all {
+		if s.name != lastName {
+			kind := "counter"
+			if s.gauge {
+				kind = "gauge"
+			}
+			fmt.Fprintf(w, "# TYPE %s%s %s\n", prometheusPrefix, s.name, kind)
+			lastName = s.name
+		}
+		fmt.Fprintf(w, "%s%s%s %g\n", prometheusPrefix, s.name, s.labels, s.value)
+	}
+}
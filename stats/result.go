@@ -0,0 +1,356 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is the machine readable result of one run of a command.
+type Result struct {
+	Command string            `json:"command"`
+	Options map[string]string `json:"options"`
+	Start   time.Time         `json:"start"`
+	End     time.Time         `json:"end"`
+	Error   string            `json:"error,omitempty"`
+	Metrics []MetricResult    `json:"metrics"`
+}
+
+// MetricResult contains the values for one name of a Collector.
+//
+// All durations are in milliseconds.
+type MetricResult struct {
+	Name   string         `json:"name"`
+	Count  int64          `json:"count"`
+	Errors map[string]int `json:"errors"`
+	Mean   float64        `json:"mean_ms"`
+	P50    float64        `json:"p50_ms"`
+	P90    float64        `json:"p90_ms"`
+	P95    float64        `json:"p95_ms"`
+	P99    float64        `json:"p99_ms"`
+	Max    float64        `json:"max_ms"`
+}
+
+// ErrorCount returns the amount of all errors.
+func (m MetricResult) ErrorCount() int {
+	var count int
+	for _, n := range m.Errors {
+		count += n
+	}
+	return count
+}
+
+// ErrorRate returns the share of failed requests as a value between 0 and 1.
+//
+// Transport errors are not part of Count, because they have no latency.
+func (m MetricResult) ErrorRate() float64 {
+	total := m.Count + int64(m.Errors["transport"])
+	if total == 0 {
+		return 0
+	}
+	return float64(m.ErrorCount()) / float64(total)
+}
+
+// Metric returns the metric with the given name.
+func (r Result) Metric(name string) (MetricResult, bool) {
+	for _, m := range r.Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return MetricResult{}, false
+}
+
+// Metrics returns the values of all names.
+func (c *Collector) Metrics() []MetricResult {
+	names := c.Names()
+	if len(names) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := make([]MetricResult, len(names))
+	for i, name := range names {
+		m := c.metrics[name]
+		h := &m.histogram
+
+		errors := make(map[string]int, len(m.errors))
+		for kind, n := range m.errors {
+			errors[kind] = n
+		}
+
+		results[i] = MetricResult{
+			Name:   name,
+			Count:  h.Count(),
+			Errors: errors,
+			Mean:   milliseconds(h.Mean()),
+			P50:    milliseconds(h.Percentile(50)),
+			P90:    milliseconds(h.Percentile(90)),
+			P95:    milliseconds(h.Percentile(95)),
+			P99:    milliseconds(h.Percentile(99)),
+			Max:    milliseconds(h.Max()),
+		}
+	}
+	return results
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteFile writes the result to a file. The format is chosen by the file
+// extension. Supported are .json and .csv.
+func (r Result) WriteFile(path string) error {
+	var write func(io.Writer) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		write = r.WriteJSON
+	case ".csv":
+		write = r.WriteCSV
+	default:
+		return fmt.Errorf("unknown file extension %q, use .json or .csv", ext)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing result: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes the result as json.
+func (r Result) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+var csvHeader = []string{
+	"command",
+	"options",
+	"start",
+	"end",
+	"error",
+	"name",
+	"count",
+	"errors",
+	"mean_ms",
+	"p50_ms",
+	"p90_ms",
+	"p95_ms",
+	"p99_ms",
+	"max_ms",
+}
+
+// WriteCSV writes the result as csv with one row per metric.
+//
+// The fields of the result are repeated on each row. Options and errors are
+// encoded like an url query.
+func (r Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	metrics := r.Metrics
+	if len(metrics) == 0 {
+		// Write one row so the information about the command is not lost.
+		metrics = []MetricResult{{}}
+	}
+
+	for _, m := range metrics {
+		errors := make(map[string]string, len(m.Errors))
+		for kind, n := range m.Errors {
+			errors[kind] = strconv.Itoa(n)
+		}
+
+		record := []string{
+			r.Command,
+			encodePairs(r.Options),
+			r.Start.Format(time.RFC3339Nano),
+			r.End.Format(time.RFC3339Nano),
+			r.Error,
+			m.Name,
+			strconv.FormatInt(m.Count, 10),
+			encodePairs(errors),
+			formatFloat(m.Mean),
+			formatFloat(m.P50),
+			formatFloat(m.P90),
+			formatFloat(m.P95),
+			formatFloat(m.P99),
+			formatFloat(m.Max),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadResultFile reads a result that was written with WriteFile.
+func ReadResultFile(path string) (Result, error) {
+	var read func(io.Reader) (Result, error)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		read = ReadJSON
+	case ".csv":
+		read = ReadCSV
+	default:
+		return Result{}, fmt.Errorf("unknown file extension %q, use .json or .csv", ext)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	return read(f)
+}
+
+// ReadJSON reads a result written by WriteJSON.
+func ReadJSON(r io.Reader) (Result, error) {
+	var result Result
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return Result{}, fmt.Errorf("decoding json: %w", err)
+	}
+	return result, nil
+}
+
+// ReadCSV reads a result written by WriteCSV.
+func ReadCSV(r io.Reader) (Result, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return Result{}, fmt.Errorf("reading csv: %w", err)
+	}
+
+	if len(records) < 2 {
+		return Result{}, fmt.Errorf("csv has no rows")
+	}
+
+	column := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := column[name]; !ok {
+			return Result{}, fmt.Errorf("csv has no column %s", name)
+		}
+	}
+
+	var result Result
+	for i, record := range records[1:] {
+		field := func(name string) string {
+			return record[column[name]]
+		}
+
+		if i == 0 {
+			result.Command = field("command")
+			if result.Options, err = decodePairs(field("options")); err != nil {
+				return Result{}, fmt.Errorf("parsing options: %w", err)
+			}
+			result.Error = field("error")
+
+			if result.Start, err = time.Parse(time.RFC3339Nano, field("start")); err != nil {
+				return Result{}, fmt.Errorf("parsing start time: %w", err)
+			}
+
+			if result.End, err = time.Parse(time.RFC3339Nano, field("end")); err != nil {
+				return Result{}, fmt.Errorf("parsing end time: %w", err)
+			}
+		}
+
+		if field("name") == "" {
+			continue
+		}
+
+		m := MetricResult{
+			Name:   field("name"),
+			Errors: make(map[string]int),
+		}
+
+		if m.Count, err = strconv.ParseInt(field("count"), 10, 64); err != nil {
+			return Result{}, fmt.Errorf("parsing count of %s: %w", m.Name, err)
+		}
+
+		errors, err := decodePairs(field("errors"))
+		if err != nil {
+			return Result{}, fmt.Errorf("parsing errors of %s: %w", m.Name, err)
+		}
+
+		for kind, value := range errors {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return Result{}, fmt.Errorf("parsing error count of %s: %w", m.Name, err)
+			}
+			m.Errors[kind] = n
+		}
+
+		for _, value := range []struct {
+			column string
+			target *float64
+		}{
+			{"mean_ms", &m.Mean},
+			{"p50_ms", &m.P50},
+			{"p90_ms", &m.P90},
+			{"p95_ms", &m.P95},
+			{"p99_ms", &m.P99},
+			{"max_ms", &m.Max},
+		} {
+			if *value.target, err = strconv.ParseFloat(field(value.column), 64); err != nil {
+				return Result{}, fmt.Errorf("parsing %s of %s: %w", value.column, m.Name, err)
+			}
+		}
+
+		result.Metrics = append(result.Metrics, m)
+	}
+
+	return result, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+// encodePairs encodes a map like an url query.
+func encodePairs(m map[string]string) string {
+	values := make(url.Values, len(m))
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// decodePairs is the reverse of encodePairs.
+func decodePairs(s string) (map[string]string, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(values))
+	for k := range values {
+		m[k] = values.Get(k)
+	}
+	return m, nil
+}
@@ -0,0 +1,69 @@
+package stats_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+func TestResultCSVRoundTrip(t *testing.T) {
+	start := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	result := stats.Result{
+		Command: "vote",
+		Options: map[string]string{
+			"amount": "100",
+			"body":   `{"key": "value with space"}`,
+			"mix":    "a.json:70,b.json:30",
+			"action": "line one\nline two & key=value",
+		},
+		Start: start,
+		End:   start.Add(time.Minute),
+		Metrics: []stats.MetricResult{
+			{
+				Name:   "/system/vote",
+				Count:  100,
+				Errors: map[string]int{"status 400": 3, "transport": 1, "bad, kind=x&y": 2},
+				Mean:   12.5,
+				P50:    10,
+				P90:    20,
+				P95:    25,
+				P99:    40,
+				Max:    100,
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := result.WriteCSV(buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+
+	got, err := stats.ReadCSV(buf)
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+
+	if got.Command != "vote" || !got.Start.Equal(result.Start) {
+		t.Errorf("got result %v, expected %v", got, result)
+	}
+
+	if !reflect.DeepEqual(got.Options, result.Options) {
+		t.Errorf("got options %q, expected %q", got.Options, result.Options)
+	}
+
+	m, ok := got.Metric("/system/vote")
+	if !ok {
+		t.Fatalf("metric /system/vote is missing")
+	}
+
+	if m.P95 != 25 || !reflect.DeepEqual(m.Errors, result.Metrics[0].Errors) {
+		t.Errorf("got metric %v, expected %v", m, result.Metrics[0])
+	}
+
+	if rate := m.ErrorRate(); rate != 6.0/101 {
+		t.Errorf("got error rate %f, expected %f", rate, 6.0/101)
+	}
+}
@@ -0,0 +1,188 @@
+// Package stats collects the latency of requests and prints a summary.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Collector collects latencies and errors for different names.
+//
+// The name is usually the path of a request. A nil Collector is valid and
+// ignores all values.
+type Collector struct {
+	parent *Collector
+
+	mu      sync.Mutex
+	metrics map[string]*metric
+	series  map[string]*series
+}
+
+type metric struct {
+	histogram Histogram
+	errors    map[string]int
+}
+
+// New initializes a Collector.
+func New() *Collector {
+	return &Collector{
+		metrics: make(map[string]*metric),
+		series:  make(map[string]*series),
+	}
+}
+
+// Sub returns a new Collector. All values that are added to the new Collector
+// are also added to c.
+func (c *Collector) Sub() *Collector {
+	sub := New()
+	sub.parent = c
+	return sub
+}
+
+// metric returns the metric for the name. Has to be called with the lock.
+func (c *Collector) metric(name string) *metric {
+	m, ok := c.metrics[name]
+	if !ok {
+		m = &metric{errors: make(map[string]int)}
+		c.metrics[name] = m
+	}
+	return m
+}
+
+// Add records the duration for the name.
+func (c *Collector) Add(name string, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.parent.Add(name, d)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metric(name).histogram.Add(d)
+}
+
+// AddError counts an error of the given kind for the name.
+//
+// The kind is something like "status 404" or "transport".
+func (c *Collector) AddError(name string, kind string) {
+	if c == nil {
+		return
+	}
+	c.parent.AddError(name, kind)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metric(name).errors[kind]++
+}
+
+// Histogram returns a copy of the histogram for the name.
+func (c *Collector) Histogram(name string) Histogram {
+	if c == nil {
+		return Histogram{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.metrics[name]
+	if !ok {
+		return Histogram{}
+	}
+
+	var h Histogram
+	h.Merge(&m.histogram)
+	return h
+}
+
+// Names returns all names with values in alphabetical order.
+func (c *Collector) Names() []string {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.metrics))
+	for name := range c.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Print writes a table with the percentiles of all names to w.
+//
+// Does nothing, if no values where recorded.
+func (c *Collector) Print(w io.Writer) {
+	names := c.Names()
+	if len(names) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tcount\terrors\tp50\tp90\tp95\tp99\tmax\t")
+	for _, name := range names {
+		m := c.metrics[name]
+		h := &m.histogram
+
+		errCount := 0
+		for _, n := range m.errors {
+			errCount += n
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t\n",
+			name,
+			h.Count(),
+			errCount,
+			Round(h.Percentile(50)),
+			Round(h.Percentile(90)),
+			Round(h.Percentile(95)),
+			Round(h.Percentile(99)),
+			Round(h.Max()),
+		)
+	}
+	tw.Flush()
+
+	var errLines []string
+	for _, name := range names {
+		m := c.metrics[name]
+		kinds := make([]string, 0, len(m.errors))
+		for kind := range m.errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			errLines = append(errLines, fmt.Sprintf("%s: %s: %d", name, kind, m.errors[kind]))
+		}
+	}
+
+	if len(errLines) > 0 {
+		fmt.Fprintf(w, "\nErrors:\n%s\n", strings.Join(errLines, "\n"))
+	}
+}
+
+// Round rounds a duration to a precision that is readable in a table.
+func Round(d time.Duration) time.Duration {
+	switch {
+	case d > time.Second:
+		return d.Round(time.Millisecond)
+	case d > time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d
+	}
+}
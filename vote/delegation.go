@@ -0,0 +1,134 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/OpenSlides/openslides-performance/client"
+)
+
+// voter is one vote, that is sent by a client.
+type voter struct {
+	client *client.Client
+
+	// userID is the user, for whom the vote is sent. It is 0, if the client
+	// votes for its own user.
+	userID int
+}
+
+// selfVoters returns a voter for each client, that votes for itself.
+func selfVoters(clients []*client.Client) []voter {
+	voters := make([]voter, len(clients))
+	for i, c := range clients {
+		voters[i] = voter{client: c}
+	}
+	return voters
+}
+
+// delegatedVoters returns the voters with vote delegations.
+//
+// A client, whose user delegated the vote to the user of another client, does
+// not vote. The other client sends the vote instead. If the delegate is not
+// logged in, the client votes itself.
+func delegatedVoters(ctx context.Context, admin *client.Client, meetingID int, clients []*client.Client) ([]voter, error) {
+	clientByUser := make(map[int]*client.Client, len(clients))
+	userIDs := make([]int, 0, len(clients))
+	for _, c := range clients {
+		if c.UserID() != 0 {
+			clientByUser[c.UserID()] = c
+			userIDs = append(userIDs, c.UserID())
+		}
+	}
+
+	delegates, err := fetchDelegates(ctx, admin, meetingID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	voters := make([]voter, 0, len(clients))
+	delegated := 0
+	for _, c := range clients {
+		delegate, ok := clientByUser[delegates[c.UserID()]]
+		if !ok {
+			voters = append(voters, voter{client: c})
+			continue
+		}
+
+		voters = append(voters, voter{client: delegate, userID: c.UserID()})
+		delegated++
+	}
+
+	log.Printf("%d of %d votes are sent by delegates", delegated, len(voters))
+	return voters, nil
+}
+
+// fetchDelegates returns for each user, that delegated the vote in the
+// meeting, the user id of the delegate.
+func fetchDelegates(ctx context.Context, admin *client.Client, meetingID int, userIDs []int) (map[int]int, error) {
+	ids, err := json.Marshal(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("encoding user ids: %w", err)
+	}
+
+	requestBody := fmt.Sprintf(
+		`[{
+			"collection":"user",
+			"ids":%s,
+			"fields": {
+				"meeting_user_ids": {
+					"type":"relation-list",
+					"collection":"meeting_user",
+					"fields": {
+						"user_id": null,
+						"meeting_id": null,
+						"vote_delegated_to_id": {
+							"type":"relation",
+							"collection":"meeting_user",
+							"fields": {"user_id": null}
+						}
+					}
+				}
+			}
+		}]`,
+		ids,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/system/autoupdate?single=1", strings.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+
+	resp, err := admin.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getting response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decoding response body: %w", err)
+	}
+
+	delegates := make(map[int]int)
+	for key, value := range data {
+		var meetingUserID int
+		if _, err := fmt.Sscanf(key, "meeting_user/%d/vote_delegated_to_id", &meetingUserID); err != nil {
+			continue
+		}
+
+		var mID, userID, delegateMeetingUserID, delegateID int
+		json.Unmarshal(data[fmt.Sprintf("meeting_user/%d/meeting_id", meetingUserID)], &mID)
+		json.Unmarshal(data[fmt.Sprintf("meeting_user/%d/user_id", meetingUserID)], &userID)
+		json.Unmarshal(value, &delegateMeetingUserID)
+		json.Unmarshal(data[fmt.Sprintf("meeting_user/%d/user_id", delegateMeetingUserID)], &delegateID)
+
+		if mID == meetingID && delegateID != 0 {
+			delegates[userID] = delegateID
+		}
+	}
+	return delegates, nil
+}
@@ -0,0 +1,284 @@
+package vote
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// poll is the data of a poll that is needed to vote.
+type poll struct {
+	id        int
+	meetingID int
+	typ       string
+	method    string
+	optionIDs []int
+	cryptKey  []byte
+}
+
+// distribution chooses the answers of the votes.
+//
+// The answers are chosen with a smooth weighted round robin, so the sent
+// votes match the weights exactly. A random distribution chooses each answer
+// randomly.
+type distribution struct {
+	random  bool
+	answers []string
+	weights []int
+
+	// current is the state of the round robin for each option. Each option
+	// gets its answers in the ratio of the weights.
+	current map[int][]int
+}
+
+// parseDistribution parses a distribution like Y=60,N=30,A=10.
+//
+// An answer without a weight has the weight 1. The value "random" chooses
+// random answers.
+func parseDistribution(s string) (*distribution, error) {
+	s = strings.TrimSpace(s)
+	if s == "random" {
+		return &distribution{random: true}, nil
+	}
+
+	var d distribution
+	for _, part := range strings.Split(s, ",") {
+		answer, rawWeight, found := strings.Cut(strings.TrimSpace(part), "=")
+		weight := 1
+		if found {
+			w, err := strconv.Atoi(rawWeight)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight %q for answer %s", rawWeight, answer)
+			}
+			weight = w
+		}
+
+		if answer == "" {
+			return nil, fmt.Errorf("empty answer in %q", s)
+		}
+
+		if weight == 0 {
+			continue
+		}
+
+		d.answers = append(d.answers, answer)
+		d.weights = append(d.weights, weight)
+	}
+
+	if len(d.answers) == 0 {
+		return nil, fmt.Errorf("distribution %q has no answers", s)
+	}
+
+	d.current = make(map[int][]int)
+	return &d, nil
+}
+
+// validate checks, that the answers of the distribution can be used for the
+// poll.
+func (d *distribution) validate(p poll) error {
+	for _, answer := range d.answers {
+		switch p.method {
+		case "YN", "YNA":
+			if len(answer) != 1 || !strings.Contains(p.method, answer) {
+				return fmt.Errorf("answer %s is not possible for poll method %s", answer, p.method)
+			}
+
+		default:
+			if answer == "Y" || answer == "N" || answer == "A" {
+				continue
+			}
+
+			optionID, err := strconv.Atoi(answer)
+			if err != nil || !containsInt(p.optionIDs, optionID) {
+				return fmt.Errorf("answer %s is not an option of poll %d", answer, p.id)
+			}
+		}
+	}
+	return nil
+}
+
+// next returns the next answer for the option. choices are the possible
+// answers for a random distribution.
+func (d *distribution) next(optionID int, choices []string) string {
+	if d.random {
+		return choices[rand.Intn(len(choices))]
+	}
+
+	current := d.current[optionID]
+	if current == nil {
+		current = make([]int, len(d.answers))
+		d.current[optionID] = current
+	}
+
+	total := 0
+	best := 0
+	for i, w := range d.weights {
+		total += w
+		current[i] += w
+		if current[i] > current[best] {
+			best = i
+		}
+	}
+	current[best] -= total
+	return d.answers[best]
+}
+
+// vote returns the value of one vote for the poll.
+//
+// For the methods YN and YNA, each option gets an answer. For other methods,
+// the answer is an option that gets one vote, or N or A as a global answer. Y
+// means a random option.
+func (d *distribution) vote(p poll) (ballot, error) {
+	switch p.method {
+	case "YN", "YNA":
+		choices := strings.Split(p.method, "")
+		b := ballot{options: make(map[int]string, len(p.optionIDs))}
+		for _, optionID := range p.optionIDs {
+			b.options[optionID] = d.next(optionID, choices)
+		}
+		return b, nil
+
+	default:
+		choices := make([]string, len(p.optionIDs))
+		for i, optionID := range p.optionIDs {
+			choices[i] = strconv.Itoa(optionID)
+		}
+
+		answer := d.next(0, choices)
+		switch answer {
+		case "N", "A":
+			return ballot{global: answer}, nil
+		case "Y":
+			answer = choices[rand.Intn(len(choices))]
+		}
+
+		optionID, err := strconv.Atoi(answer)
+		if err != nil {
+			return ballot{}, fmt.Errorf("invalid answer %s", answer)
+		}
+		return ballot{options: map[int]string{optionID: "Y"}}, nil
+	}
+}
+
+// ballot is one vote.
+type ballot struct {
+	// options is the answer for each option.
+	options map[int]string
+
+	// global is the answer for the whole poll, e.g. A for abstain.
+	global string
+}
+
+// value returns the json value that is sent to the vote service.
+func (b ballot) value(method string) ([]byte, error) {
+	if b.global != "" {
+		return json.Marshal(b.global)
+	}
+
+	switch method {
+	case "YN", "YNA":
+		return json.Marshal(b.options)
+
+	default:
+		amounts := make(map[int]int, len(b.options))
+		for optionID := range b.options {
+			amounts[optionID] = 1
+		}
+		return json.Marshal(amounts)
+	}
+}
+
+// tally counts the votes that were accepted by the vote service.
+type tally struct {
+	mu      sync.Mutex
+	votes   int
+	options map[int]map[string]int
+	global  map[string]int
+
+	// accepted are the indexes of the voters, whose vote was accepted.
+	accepted []int
+}
+
+func newTally() *tally {
+	return &tally{
+		options: make(map[int]map[string]int),
+		global:  make(map[string]int),
+	}
+}
+
+// accept adds the vote of the voter with the index i.
+func (t *tally) accept(i int, b ballot) {
+	t.mu.Lock()
+	t.accepted = append(t.accepted, i)
+	t.mu.Unlock()
+
+	t.add(b)
+}
+
+func (t *tally) add(b ballot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.votes++
+	if b.global != "" {
+		t.global[b.global]++
+		return
+	}
+
+	for optionID, answer := range b.options {
+		if t.options[optionID] == nil {
+			t.options[optionID] = make(map[string]int)
+		}
+		t.options[optionID][answer]++
+	}
+}
+
+// String returns the answers for each option.
+func (t *tally) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	optionIDs := make([]int, 0, len(t.options))
+	for optionID := range t.options {
+		optionIDs = append(optionIDs, optionID)
+	}
+	sort.Ints(optionIDs)
+
+	lines := []string{fmt.Sprintf("Accepted votes: %d", t.votes)}
+	for _, optionID := range optionIDs {
+		lines = append(lines, fmt.Sprintf("Option %d: %s", optionID, formatAnswers(t.options[optionID])))
+	}
+
+	if len(t.global) > 0 {
+		lines = append(lines, fmt.Sprintf("Global: %s", formatAnswers(t.global)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func formatAnswers(answers map[string]int) string {
+	keys := make([]string, 0, len(answers))
+	for answer := range answers {
+		keys = append(keys, answer)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, answer := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", answer, answers[answer])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package vote
+
+import "testing"
+
+func TestDistribution(t *testing.T) {
+	dist, err := parseDistribution("Y=60,N=30,A=10")
+	if err != nil {
+		t.Fatalf("parseDistribution: %v", err)
+	}
+
+	p := poll{id: 1, method: "YNA", optionIDs: []int{1}}
+	if err := dist.validate(p); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	sent := newTally()
+	for i := 0; i < 100; i++ {
+		b, err := dist.vote(p)
+		if err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+		sent.add(b)
+	}
+
+	got := sent.options[1]
+	if got["Y"] != 60 || got["N"] != 30 || got["A"] != 10 {
+		t.Errorf("got answers %v, expected Y: 60, N: 30, A: 10", got)
+	}
+}
+
+func TestDistributionInvalid(t *testing.T) {
+	dist, err := parseDistribution("A")
+	if err != nil {
+		t.Fatalf("parseDistribution: %v", err)
+	}
+
+	if err := dist.validate(poll{method: "YN", optionIDs: []int{1}}); err == nil {
+		t.Errorf("validate returned no error for abstain on a YN poll")
+	}
+}
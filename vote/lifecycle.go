@@ -0,0 +1,388 @@
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/createusers"
+	"github.com/OpenSlides/openslides-performance/stats"
+	"github.com/OpenSlides/vote-decrypt/crypto"
+)
+
+// createPoll creates a topic and a poll for it. It returns the id of the
+// poll.
+//
+// Analog polls get their results from the distribution for all users and are
+// published at once.
+func (o Options) createPoll(ctx context.Context, admin *client.Client, dist *distribution) (int, error) {
+	groupID, err := createusers.DelegateGroup(ctx, admin, o.MeetingID)
+	if err != nil {
+		return 0, fmt.Errorf("fetching delegates group: %w", err)
+	}
+
+	title := fmt.Sprintf("Performance poll %s", time.Now().Format(time.TimeOnly))
+	topicID, err := backendAction(ctx, admin, "topic.create", map[string]any{
+		"meeting_id": o.MeetingID,
+		"title":      title,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("creating topic: %w", err)
+	}
+
+	options := make([]map[string]any, o.PollOptions)
+	for i := range options {
+		options[i] = map[string]any{"text": fmt.Sprintf("Option %d", i+1)}
+	}
+
+	data := map[string]any{
+		"meeting_id":              o.MeetingID,
+		"title":                   title,
+		"type":                    o.CreatePoll,
+		"pollmethod":              o.PollMethod,
+		"content_object_id":       fmt.Sprintf("topic/%d", topicID),
+		"onehundred_percent_base": "disabled",
+		"entitled_group_ids":      []int{groupID},
+		"options":                 options,
+	}
+
+	if o.PollMethod == "Y" {
+		data["global_no"] = true
+		data["global_abstain"] = true
+	}
+
+	if o.CreatePoll == "analog" {
+		// The option ids are not known before the poll is created. The
+		// distribution uses the position of the options instead.
+		optionIDs := make([]int, len(options))
+		for i := range options {
+			optionIDs[i] = i + 1
+		}
+
+		results := newTally()
+		for i := 0; i < o.Amount; i++ {
+			b, err := dist.vote(poll{method: o.PollMethod, optionIDs: optionIDs})
+			if err != nil {
+				return 0, fmt.Errorf("creating vote: %w", err)
+			}
+			results.add(b)
+		}
+
+		for i, option := range options {
+			for answer, count := range results.options[i+1] {
+				option[answer] = count
+			}
+		}
+		data["votesvalid"] = o.Amount
+		data["votescast"] = o.Amount
+		data["publish_immediately"] = true
+	}
+
+	pollID, err := backendAction(ctx, admin, "poll.create", data)
+	if err != nil {
+		return 0, fmt.Errorf("creating poll: %w", err)
+	}
+
+	return pollID, nil
+}
+
+// setPollState calls a poll action like poll.start for the poll.
+func setPollState(ctx context.Context, admin *client.Client, action string, pollID int) error {
+	if _, err := backendAction(ctx, admin, action, map[string]any{"id": pollID}); err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	return nil
+}
+
+// timed calls fn and records the duration with the name.
+func timed(collector *stats.Collector, name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	d := time.Since(start)
+
+	collector.Add(name, d)
+	if err != nil {
+		collector.AddError(name, "failed")
+		return err
+	}
+
+	log.Printf("%s in %v", strings.ToUpper(name[:1])+name[1:], d)
+	return nil
+}
+
+// backendAction sends one action with one element of data to the backend.
+//
+// It returns the id from the result. It is 0, if the action does not return
+// an id.
+func backendAction(ctx context.Context, client *client.Client, action string, data any) (int, error) {
+	reqBody, err := json.Marshal([]map[string]any{{"action": action, "data": []any{data}}})
+	if err != nil {
+		return 0, fmt.Errorf("encoding action: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		"/system/action/handle_request",
+		strings.NewReader(string(reqBody)),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var respBody struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Results [][]*struct {
+			ID int `json:"id"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return 0, fmt.Errorf("decoding body: %w", err)
+	}
+
+	if !respBody.Success {
+		return 0, fmt.Errorf("backend returned no success: %s", respBody.Message)
+	}
+
+	if len(respBody.Results) == 0 || len(respBody.Results[0]) == 0 || respBody.Results[0][0] == nil {
+		return 0, nil
+	}
+	return respBody.Results[0][0].ID, nil
+}
+
+// pollResult is the result of a poll from the server.
+type pollResult struct {
+	state      string
+	votesValid float64
+	votesCast  float64
+
+	// options is the amount of each answer (Y, N, A) for each option.
+	options map[int]map[string]float64
+
+	// global is the amount of the global answers.
+	global map[string]float64
+
+	// votesRaw are the decrypted votes of a cryptographic poll, signed with
+	// votesSignature by vote-decrypt.
+	votesRaw       string
+	votesSignature []byte
+}
+
+// fetchPollResult reads the result of a poll.
+func fetchPollResult(ctx context.Context, client *client.Client, pollID int) (pollResult, error) {
+	optionFields := `{"yes":null,"no":null,"abstain":null}`
+	requestBody := fmt.Sprintf(
+		`[{
+			"collection":"poll",
+			"ids":[%d],
+			"fields": {
+				"state": null,
+				"votesvalid": null,
+				"votescast": null,
+				"votes_raw": null,
+				"votes_signature": null,
+				"option_ids": {"type":"relation-list","collection":"option","fields":%s},
+				"global_option_id": {"type":"relation","collection":"option","fields":%s}
+			}
+		}]`,
+		pollID,
+		optionFields,
+		optionFields,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/system/autoupdate?single=1", strings.NewReader(requestBody))
+	if err != nil {
+		return pollResult{}, fmt.Errorf("building request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return pollResult{}, fmt.Errorf("getting response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return pollResult{}, fmt.Errorf("decoding response body: %w", err)
+	}
+
+	pollFQID := fmt.Sprintf("poll/%d", pollID)
+	result := pollResult{
+		votesValid: parseDecimal(data[pollFQID+"/votesvalid"]),
+		votesCast:  parseDecimal(data[pollFQID+"/votescast"]),
+		options:    make(map[int]map[string]float64),
+	}
+
+	if err := json.Unmarshal(data[pollFQID+"/state"], &result.state); err != nil {
+		return pollResult{}, fmt.Errorf("poll %d does not exist", pollID)
+	}
+
+	json.Unmarshal(data[pollFQID+"/votes_raw"], &result.votesRaw)
+	json.Unmarshal(data[pollFQID+"/votes_signature"], &result.votesSignature)
+
+	answers := func(optionID int) map[string]float64 {
+		optionFQID := fmt.Sprintf("option/%d", optionID)
+		return map[string]float64{
+			"Y": parseDecimal(data[optionFQID+"/yes"]),
+			"N": parseDecimal(data[optionFQID+"/no"]),
+			"A": parseDecimal(data[optionFQID+"/abstain"]),
+		}
+	}
+
+	var optionIDs []int
+	json.Unmarshal(data[pollFQID+"/option_ids"], &optionIDs)
+	for _, optionID := range optionIDs {
+		result.options[optionID] = answers(optionID)
+	}
+
+	var globalOptionID int
+	if err := json.Unmarshal(data[pollFQID+"/global_option_id"], &globalOptionID); err == nil && globalOptionID != 0 {
+		result.global = answers(globalOptionID)
+	}
+
+	return result, nil
+}
+
+// verifySignature checks, that the decrypted votes of a cryptographic poll
+// were signed with the main key of vote-decrypt.
+func verifySignature(ctx context.Context, client *client.Client, result pollResult) error {
+	requestBody := `[{
+		"collection":"organization",
+		"ids":[1],
+		"fields": {"vote_decrypt_public_main_key": null}
+	}]`
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/system/autoupdate?single=1", strings.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("getting response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+
+	var mainKey []byte
+	if err := json.Unmarshal(data["organization/1/vote_decrypt_public_main_key"], &mainKey); err != nil || len(mainKey) == 0 {
+		return fmt.Errorf("organization has no public main key")
+	}
+
+	if !crypto.Verify(mainKey, []byte(result.votesRaw), result.votesSignature) {
+		return fmt.Errorf("invalid signature of the decrypted votes")
+	}
+	return nil
+}
+
+// String returns the result in the same format as the tally.
+func (r pollResult) String() string {
+	optionIDs := make([]int, 0, len(r.options))
+	for optionID := range r.options {
+		optionIDs = append(optionIDs, optionID)
+	}
+	sort.Ints(optionIDs)
+
+	lines := []string{fmt.Sprintf("State: %s, votes cast: %g, votes valid: %g", r.state, r.votesCast, r.votesValid)}
+	for _, optionID := range optionIDs {
+		lines = append(lines, fmt.Sprintf("Option %d: %s", optionID, formatAmounts(r.options[optionID])))
+	}
+
+	if r.global != nil {
+		lines = append(lines, fmt.Sprintf("Global: %s", formatAmounts(r.global)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// verify compares the result with the votes, that were accepted by the vote
+// service. It returns an error that lists all differences.
+//
+// If onlyCount is true, only the amount of votes is compared. This is used for
+// cryptographic polls, where the server does not know the answers.
+func (r pollResult) verify(sent *tally, onlyCount bool) error {
+	sent.mu.Lock()
+	defer sent.mu.Unlock()
+
+	var problems []string
+	if r.votesCast != float64(sent.votes) {
+		problems = append(problems, fmt.Sprintf("votes cast: got %g, sent %d", r.votesCast, sent.votes))
+	}
+
+	if r.votesValid != float64(sent.votes) {
+		problems = append(problems, fmt.Sprintf("votes valid: got %g, sent %d", r.votesValid, sent.votes))
+	}
+
+	if !onlyCount {
+		optionIDs := make([]int, 0, len(r.options))
+		for optionID := range r.options {
+			optionIDs = append(optionIDs, optionID)
+		}
+		sort.Ints(optionIDs)
+
+		for _, optionID := range optionIDs {
+			problems = append(problems, compareAnswers(fmt.Sprintf("option %d", optionID), r.options[optionID], sent.options[optionID])...)
+		}
+
+		for optionID := range sent.options {
+			if _, ok := r.options[optionID]; !ok {
+				problems = append(problems, fmt.Sprintf("option %d: sent votes, but it is not in the result", optionID))
+			}
+		}
+
+		problems = append(problems, compareAnswers("global", r.global, sent.global)...)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("result does not match the sent votes:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// compareAnswers returns a line for each answer with a different amount.
+func compareAnswers(name string, got map[string]float64, sent map[string]int) []string {
+	var problems []string
+	for _, answer := range []string{"Y", "N", "A"} {
+		if got[answer] != float64(sent[answer]) {
+			problems = append(problems, fmt.Sprintf("%s %s: got %g, sent %d", name, answer, got[answer], sent[answer]))
+		}
+	}
+	return problems
+}
+
+func formatAmounts(amounts map[string]float64) string {
+	return fmt.Sprintf("A: %g, N: %g, Y: %g", amounts["A"], amounts["N"], amounts["Y"])
+}
+
+// parseDecimal parses a decimal value like "12.000000". OpenSlides sends
+// results as strings.
+func parseDecimal(raw json.RawMessage) float64 {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+
+	var f float64
+	json.Unmarshal(raw, &f)
+	return f
+}
@@ -0,0 +1,35 @@
+package vote
+
+import "testing"
+
+func TestPollResultVerify(t *testing.T) {
+	sent := newTally()
+	sent.add(ballot{options: map[int]string{1: "Y"}})
+	sent.add(ballot{options: map[int]string{1: "Y"}})
+	sent.add(ballot{global: "A"})
+
+	result := pollResult{
+		votesValid: 3,
+		votesCast:  3,
+		options:    map[int]map[string]float64{1: {"Y": 2}, 2: {}},
+		global:     map[string]float64{"A": 1},
+	}
+
+	if err := result.verify(sent, false); err != nil {
+		t.Errorf("verify returned unexpected error: %v", err)
+	}
+
+	result.options[1]["Y"] = 3
+	if err := result.verify(sent, false); err == nil {
+		t.Errorf("verify returned no error for a double counted vote")
+	}
+
+	if err := result.verify(sent, true); err != nil {
+		t.Errorf("verify with onlyCount returned unexpected error: %v", err)
+	}
+
+	result.votesValid = 2
+	if err := result.verify(sent, true); err == nil {
+		t.Errorf("verify returned no error for a lost vote")
+	}
+}
@@ -0,0 +1,184 @@
+package vote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/rate"
+	"github.com/OpenSlides/openslides-performance/stats"
+	"github.com/vbauerster/mpb/v7"
+)
+
+// LoginOptions configures the login of many users.
+//
+// It is embedded in the options of the commands that use MassLogin.
+type LoginOptions struct {
+	LoginConcurrency int    `help:"Maximum amount of logins at the same time. Default is all at once." group:"Login"`
+	LoginRate        string `help:"Start the logins with this rate, e.g. 20/s or 1/100ms. Default is as fast as possible." group:"Login"`
+	LoginMaxFailures int    `help:"Abort, if more logins fail. Default is to continue with the users, that are logged in." default:"-1" group:"Login"`
+}
+
+// LoginFailures are the users, that could not log in, by the index of their
+// client.
+type LoginFailures map[int]string
+
+// Remove returns the clients without the failed ones.
+func (f LoginFailures) Remove(clients []*client.Client) []*client.Client {
+	loggedIn := make([]*client.Client, 0, len(clients)-len(f))
+	for i, c := range clients {
+		if _, failed := f[i]; !failed {
+			loggedIn = append(loggedIn, c)
+		}
+	}
+	return loggedIn
+}
+
+// String returns the names of the failed users.
+func (f LoginFailures) String() string {
+	idx := make([]int, 0, len(f))
+	for i := range f {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	const maxNames = 10
+	names := make([]string, 0, maxNames+1)
+	for _, i := range idx {
+		if len(names) == maxNames {
+			names = append(names, fmt.Sprintf("and %d more", len(idx)-maxNames))
+			break
+		}
+		names = append(names, f[i])
+	}
+	return strings.Join(names, ", ")
+}
+
+// MassLogin logs in a list of clients.
+//
+// The client with the index i logs in as the user basename{i+1}. If meetingID
+// is greater than 0, the name is prefixed with m{meetingID}.
+//
+// It returns the users, that could not log in. If more logins fail than
+// allowed by --login-max-failures, the other logins are canceled and an error
+// is returned.
+func MassLogin(ctx context.Context, clients []*client.Client, meetingID int, basename string, password string, opts LoginOptions) (LoginFailures, error) {
+	var interval time.Duration
+	if opts.LoginRate != "" {
+		perSecond, err := rate.ParseRate(opts.LoginRate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing login rate: %w", err)
+		}
+		interval = time.Duration(float64(time.Second) / perSecond)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var limit chan struct{}
+	if opts.LoginConcurrency > 0 {
+		limit = make(chan struct{}, opts.LoginConcurrency)
+	}
+
+	var mu sync.Mutex
+	failed := make(LoginFailures)
+	var latency stats.Histogram
+	var aborted bool
+
+	var wgLogin sync.WaitGroup
+	progress := mpb.New(mpb.WithWaitGroup(&wgLogin))
+	loginBar := progress.AddBar(int64(len(clients)))
+
+	login := func(i int) {
+		client := clients[i]
+
+		username := fmt.Sprintf("%s%d", basename, i+1)
+		if meetingID > 0 {
+			username = fmt.Sprintf("m%d%s", meetingID, username)
+		}
+
+		start := time.Now()
+		err := client.LoginWithCredentials(ctx, username, password)
+		d := time.Since(start)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("Login failed for user %s: %v", username, err)
+			failed[i] = username
+			if opts.LoginMaxFailures >= 0 && len(failed) > opts.LoginMaxFailures {
+				aborted = true
+				cancel()
+			}
+			return
+		}
+
+		latency.Add(d)
+		loginBar.Increment()
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i := 0; i < len(clients) && ctx.Err() == nil; i++ {
+		if interval > 0 {
+			timer.Reset(time.Until(start.Add(time.Duration(i) * interval)))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				continue
+			}
+		}
+
+		if limit != nil {
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				continue
+			}
+		}
+
+		wgLogin.Add(1)
+		go func(i int) {
+			defer wgLogin.Done()
+			if limit != nil {
+				defer func() { <-limit }()
+			}
+			login(i)
+		}(i)
+	}
+
+	wgLogin.Wait()
+	if loginBar.Current() < int64(len(clients)) {
+		loginBar.Abort(false)
+	}
+	progress.Wait()
+
+	if latency.Count() > 0 {
+		log.Printf(
+			"Login latency: p50 %v, p90 %v, p99 %v, max %v",
+			latency.Percentile(50), latency.Percentile(90), latency.Percentile(99), latency.Max(),
+		)
+	}
+
+	if aborted {
+		return failed, fmt.Errorf("%d logins failed, more then --login-max-failures %d: %s", len(failed), opts.LoginMaxFailures, failed)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return failed, err
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,204 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+)
+
+// negativeTest sends votes, that the vote service has to reject, and checks
+// the status codes of the responses.
+type negativeTest struct {
+	cfg    client.Config
+	amount int
+
+	mu         sync.Mutex
+	rejected   map[string]int
+	unexpected []string
+}
+
+func newNegativeTest(cfg client.Config, amount int) *negativeTest {
+	return &negativeTest{
+		cfg:      cfg,
+		amount:   amount,
+		rejected: make(map[string]int),
+	}
+}
+
+// during sends malformed and unauthorized votes. It is called while the valid
+// votes are sent.
+//
+// Malformed votes are sent by the voters. Unauthorized votes are sent without
+// a login or for a user, that did not delegate the vote.
+func (n *negativeTest) during(ctx context.Context, p poll, voters []voter, foreignUserID int) error {
+	anonymous, err := client.New(n.cfg)
+	if err != nil {
+		return fmt.Errorf("creating anonymous client: %w", err)
+	}
+
+	malformed := []string{`{"value": `}
+	if p.typ != "cryptographic" {
+		// The vote service can only validate votes, that are not encrypted.
+		malformed = append(malformed, `{"value": {"0": "Y"}}`, `{"value": "X"}`)
+	}
+
+	valid, err := votePayload(p, voter{}, validBallot(p))
+	if err != nil {
+		return err
+	}
+
+	foreign, err := votePayload(p, voter{userID: foreignUserID}, validBallot(p))
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n.amount; i++ {
+		c := voters[i%len(voters)].client
+
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			n.send(ctx, c, "malformed", p.id, malformed[i%len(malformed)], 400)
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			n.send(ctx, anonymous, "anonymous", p.id, valid, 401, 403)
+		}()
+
+		go func() {
+			defer wg.Done()
+			n.send(ctx, c, "foreign user", p.id, foreign, 403)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// again sends a second vote for voters, whose vote was accepted.
+//
+// kind is duplicate, if the poll is still started, or late, if it was
+// stopped.
+func (n *negativeTest) again(ctx context.Context, kind string, p poll, voters []voter, sent *tally, expect ...int) error {
+	sent.mu.Lock()
+	accepted := append([]int(nil), sent.accepted...)
+	sent.mu.Unlock()
+
+	if len(accepted) > n.amount {
+		accepted = accepted[:n.amount]
+	}
+
+	var wg sync.WaitGroup
+	for _, i := range accepted {
+		payload, err := votePayload(p, voters[i], validBallot(p))
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func(c *client.Client) {
+			defer wg.Done()
+			n.send(ctx, c, kind, p.id, payload, expect...)
+		}(voters[i].client)
+	}
+	wg.Wait()
+	return nil
+}
+
+// send sends one vote, that has to be rejected with one of the expected
+// status codes.
+func (n *negativeTest) send(ctx context.Context, c *client.Client, kind string, pollID int, body string, expect ...int) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("/system/vote?id=%d", pollID), strings.NewReader(body))
+	if err != nil {
+		n.fail(fmt.Sprintf("%s: creating request: %v", kind, err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The probe is sent without a token refresh, so each probe is one request
+	// and it is not counted as a vote.
+	start := time.Now()
+	resp, err := c.DoOnce(req)
+	d := time.Since(start)
+
+	if err == nil {
+		resp.Body.Close()
+		n.fail(fmt.Sprintf("%s: vote was accepted", kind))
+		return
+	}
+
+	var errStatus client.HTTPStatusError
+	if !errors.As(err, &errStatus) {
+		if ctx.Err() == nil {
+			n.fail(fmt.Sprintf("%s: %v", kind, err))
+		}
+		return
+	}
+
+	if !containsInt(expect, errStatus.StatusCode) {
+		n.fail(fmt.Sprintf("%s: got status %d, expected %v", kind, errStatus.StatusCode, expect))
+		return
+	}
+
+	n.cfg.Stats.Add("vote rejected "+kind, d)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.rejected[kind]++
+}
+
+func (n *negativeTest) fail(msg string) {
+	n.cfg.Stats.Inc("vote_unexpected_responses_total")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.unexpected = append(n.unexpected, msg)
+}
+
+// String returns the amount of rejected votes of each kind.
+func (n *negativeTest) String() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	kinds := make([]string, 0, len(n.rejected))
+	for kind := range n.rejected {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s: %d", kind, n.rejected[kind])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// err returns an error, if a vote was not rejected as expected.
+func (n *negativeTest) err() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.unexpected) == 0 {
+		return nil
+	}
+
+	const maxLines = 10
+	lines := n.unexpected
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines:maxLines], fmt.Sprintf("and %d more", len(n.unexpected)-maxLines))
+	}
+	return fmt.Errorf("%d invalid votes got unexpected responses:\n%s", len(n.unexpected), strings.Join(lines, "\n"))
+}
+
+// validBallot returns a ballot, that is valid for the poll.
+func validBallot(p poll) ballot {
+	return ballot{options: map[int]string{p.optionIDs[0]: "Y"}}
+}
@@ -1,13 +1,41 @@
 package vote
 
+import (
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/rate"
+)
+
 // Options is the meta information for the cli.
 type Options struct {
-	Amount        int    `help:"Amount users to use." short:"n" default:"10"`
-	PollID        int    `help:"ID of the poll to use." short:"i" default:"1"`
-	Interrupt     bool   `help:"Wait for a user input after login."`
-	Loop          bool   `help:"After the test, start it again with the logged in users."`
-	BaseName      string `help:"The name string that is concatenated with meeting id and user id, e.g. m1dummy1." default:"dummy"`
-	UsersPassword string `help:"The password used for all users" default:"pass"`
+	Amount        int           `help:"Amount users to use." short:"n" default:"10"`
+	PollID        int           `help:"ID of the poll to use." short:"i" default:"1"`
+	Interrupt     bool          `help:"Wait for a user input after login."`
+	Loop          bool          `help:"After the test, start it again with the logged in users."`
+	BaseName      string        `help:"The name string that is concatenated with meeting id and user id, e.g. m1dummy1." default:"dummy"`
+	UsersPassword string        `help:"The password used for all users" default:"pass"`
+	CreatePoll    string        `help:"Create a new poll of this type instead of using --poll-id. It is started, stopped and published by the command." enum:",analog,named,pseudoanonymous,cryptographic" default:""`
+	MeetingID     int           `help:"Meeting of the created poll." default:"1"`
+	PollMethod    string        `help:"Method of the created poll." enum:"Y,YN,YNA" default:"YNA"`
+	PollOptions   int           `help:"Amount of options of the created poll." default:"1"`
+	Negative      int           `help:"Send this many invalid votes of each kind and check, that they are rejected. Needs --create-poll or --reset-poll."`
+	Delegation    bool          `help:"Users, that got the vote of other users, vote for them. The users that delegated their vote do not vote."`
+	Distribution  string        `help:"Answers of the votes with weights, e.g. Y=60,N=30,A=10. For polls with the method Y, option ids can be used, e.g. 5=70,6=30. Use random for random answers." default:"Y"`
+	VotedInterval time.Duration `help:"While the votes are sent, each user asks the vote service in this interval, if it has voted, e.g. 1s. Default is no checks."`
+
+	Profile    string        `help:"When the votes are sent. burst: all at once. spread: evenly over --spread. normal: normal distribution around --peak. replay: the curve of the times in --replay-file." enum:"burst,spread,normal,replay" default:"burst" group:"Vote profile"`
+	Spread     time.Duration `help:"Duration in which the votes are sent for the profiles spread and normal." default:"30s" group:"Vote profile"`
+	Peak       time.Duration `help:"Time of the peak of the normal profile. Default is the middle of --spread." group:"Vote profile"`
+	PeakWidth  time.Duration `help:"Standard deviation of the normal profile. Default is a sixth of --spread." group:"Vote profile"`
+	ReplayFile string        `help:"File with the times of a real vote, one per line, as seconds or RFC 3339 timestamps." group:"Vote profile" type:"existingfile"`
+
+	Rounds     int           `help:"Run this many votes without user input. Each round needs a new poll from --create-poll, --reset-poll or --poll-ids." group:"Rounds"`
+	RoundPause time.Duration `help:"Pause between two rounds." default:"10s" group:"Rounds"`
+	PollIDs    []int         `help:"IDs of the polls to use, one for each round." name:"poll-ids" group:"Rounds"`
+	ResetPoll  bool          `help:"Reset the poll before each round and start it again. The poll is stopped after the votes." group:"Rounds"`
+
+	Login   LoginOptions `embed:""`
+	Arrival rate.Options `embed:""`
 }
 
 // Help returns the help message
@@ -15,6 +43,71 @@ func (o Options) Help() string {
 	return `This command requires, that there are many user created at the
 backend. You can use the command "create_users" for this job.
 
+The answers are chosen by --distribution. For polls with the method YN or
+YNA, each option gets an answer. For polls with the method Y, each vote is for
+one option. Y means a random option, N and A are global answers.
+
+openslides-performance vote --amount 100 --distribution Y=60,N=30,A=10
+
+With --create-poll, the command creates a poll for a new topic, starts it,
+lets the users vote, stops it and publishes it. The time of each step is
+reported. After the poll is stopped, its result is compared with the votes,
+that were accepted by the vote service. The command fails, if votes were lost
+or counted twice. For cryptographic polls, the signature of the decrypted
+votes is checked with the public main key of vote-decrypt. Analog polls get the results of the distribution and are
+published directly.
+
+openslides-performance vote --amount 100 --create-poll named --poll-options 3
+
+With --delegation, the command reads the vote delegations of the users. A user
+that delegated the vote does not vote. Instead, the user that received the
+vote sends a vote for them. Use "create-users --delegate-fraction" to create
+the delegations.
+
+openslides-performance create-users -m 1 -n 100 --delegate-fraction 0.3
+openslides-performance vote --amount 100 --delegation --create-poll named
+
+With --negative, the command sends invalid votes and checks, that the vote
+service rejects them with the expected status code. Malformed votes and votes
+without or with a foreign user are sent alongside the valid votes. Afterwards,
+users vote a second time, before and after the poll is stopped. The command
+fails, if an invalid vote is accepted or the result of the poll is changed.
+
+openslides-performance vote --amount 100 --create-poll named --negative 10
+
+With --voted-interval, all users ask the vote service periodically, if they
+have voted, like the browser of each user does. The checks run until all votes
+are sent. Afterwards, the amount of checks and the latency of the votes and
+the checks, that were sent while the checks were running, are reported. If
+no check was sent, because the interval is longer then the vote, a warning
+is printed. Compare the results of a run with and without checks to see, how
+the checks slow down the votes.
+
+openslides-performance vote --amount 100 --voted-interval 1s --report with-checks.json
+openslides-performance compare without-checks.json with-checks.json
+
+With --rounds, the command runs many votes without user input, like in a long
+assembly. Before each round, it waits --round-pause. Each round needs its own
+poll. The command creates one with --create-poll, resets the poll with
+--reset-poll or uses the next poll from --poll-ids. The time of each round is
+reported.
+
+openslides-performance vote --amount 100 --rounds 50 --round-pause 30s --create-poll named
+openslides-performance vote --amount 100 --poll-ids 5,6,7
+
+Per default, all users vote at the same time. With --profile, the votes are
+spread like in a real assembly. With --rate, the votes are sent with the given
+arrival rate.
+
+openslides-performance vote --amount 500 --profile normal --spread 1m
+openslides-performance vote --amount 500 --profile replay --replay-file times.txt
+
+The users log in at the same time. Use --login-concurrency or --login-rate to
+protect the auth service. If logins fail, the command continues with the other
+users. With --login-max-failures, it aborts instead.
+
+openslides-performance vote --amount 1000 --login-concurrency 50 --login-max-failures 10
+
 Example:
 
 openslides-performance vote --amount 100 --poll_id 42`
@@ -0,0 +1,218 @@
+package vote
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schedule calls fn for n votes. It blocks until all calls have returned.
+type schedule func(ctx context.Context, n int, fn func(ctx context.Context, i int)) error
+
+// schedule returns the function to start the votes. It returns nil, if all
+// votes are sent at once.
+func (o Options) schedule() (schedule, error) {
+	if o.Profile != "burst" && o.Arrival.Enabled() {
+		return nil, fmt.Errorf("--profile %s can not be used with --rate", o.Profile)
+	}
+
+	if o.Arrival.Enabled() {
+		return o.Arrival.Run, nil
+	}
+
+	if o.Profile == "burst" {
+		return nil, nil
+	}
+
+	if o.Profile != "replay" && o.Spread <= 0 {
+		return nil, fmt.Errorf("--spread has to be positive")
+	}
+
+	var curve []time.Duration
+	if o.Profile == "replay" {
+		var err error
+		curve, err = readReplayFile(o.ReplayFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading replay file: %w", err)
+		}
+	}
+
+	return func(ctx context.Context, n int, fn func(ctx context.Context, i int)) error {
+		var offsets []time.Duration
+		switch o.Profile {
+		case "spread":
+			offsets = spreadOffsets(n, o.Spread)
+		case "normal":
+			offsets = normalOffsets(n, o.Spread, o.Peak, o.PeakWidth)
+		case "replay":
+			offsets = replayOffsets(n, curve)
+		default:
+			return fmt.Errorf("unknown profile %q", o.Profile)
+		}
+
+		return runOffsets(ctx, offsets, fn)
+	}, nil
+}
+
+// runOffsets calls fn in a new goroutine at each offset since the start.
+// offsets have to be sorted.
+func runOffsets(ctx context.Context, offsets []time.Duration, fn func(ctx context.Context, i int)) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i, offset := range offsets {
+		timer.Reset(time.Until(start.Add(offset)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn(ctx, i)
+		}(i)
+	}
+	return nil
+}
+
+// spreadOffsets distributes n votes evenly over the duration.
+func spreadOffsets(n int, spread time.Duration) []time.Duration {
+	offsets := make([]time.Duration, n)
+	for i := range offsets {
+		offsets[i] = spread * time.Duration(i) / time.Duration(n)
+	}
+	return offsets
+}
+
+// normalOffsets distributes n votes with a normal distribution around the
+// peak.
+//
+// The votes are placed at the quantiles of the distribution, so each run has
+// the same curve. Votes outside of the spread are sent at its start or end. If
+// peak is 0, it is in the middle of the spread. If width is 0, it is a sixth
+// of the spread.
+func normalOffsets(n int, spread, peak, width time.Duration) []time.Duration {
+	if peak <= 0 {
+		peak = spread / 2
+	}
+
+	if width <= 0 {
+		width = spread / 6
+	}
+
+	offsets := make([]time.Duration, n)
+	for i := range offsets {
+		p := (float64(i) + 0.5) / float64(n)
+		z := math.Sqrt2 * math.Erfinv(2*p-1)
+
+		offset := peak + time.Duration(z*float64(width))
+		if offset < 0 {
+			offset = 0
+		}
+		if offset > spread {
+			offset = spread
+		}
+		offsets[i] = offset
+	}
+	return offsets
+}
+
+// replayOffsets distributes n votes with the same curve as the recorded
+// votes.
+//
+// curve are the sorted offsets of the recorded votes. If n differs from the
+// amount of recorded votes, the offsets are interpolated.
+func replayOffsets(n int, curve []time.Duration) []time.Duration {
+	offsets := make([]time.Duration, n)
+	if len(curve) == 1 {
+		return offsets
+	}
+
+	for i := range offsets {
+		pos := 0.0
+		if n > 1 {
+			pos = float64(i) / float64(n-1) * float64(len(curve)-1)
+		}
+
+		lower := int(pos)
+		if lower >= len(curve)-1 {
+			offsets[i] = curve[len(curve)-1]
+			continue
+		}
+
+		fraction := pos - float64(lower)
+		offsets[i] = curve[lower] + time.Duration(fraction*float64(curve[lower+1]-curve[lower]))
+	}
+	return offsets
+}
+
+// readReplayFile reads the times of recorded votes.
+//
+// The file contains one time per line. A time is a number of seconds, for
+// example a unix timestamp, or a timestamp in RFC 3339 format. Empty lines and
+// lines starting with # are ignored. If a line contains commas, only the first
+// field is used.
+//
+// It returns the sorted times relative to the first vote.
+func readReplayFile(path string) ([]time.Duration, error) {
+	if path == "" {
+		return nil, fmt.Errorf("--profile replay needs --replay-file")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	var times []time.Duration
+	scanner := bufio.NewScanner(f)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		field, _, _ := strings.Cut(line, ",")
+		field = strings.TrimSpace(field)
+
+		if seconds, err := strconv.ParseFloat(field, 64); err == nil {
+			times = append(times, time.Duration(seconds*float64(time.Second)))
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time %q", lineNr, field)
+		}
+		times = append(times, time.Duration(t.UnixNano()))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	if len(times) == 0 {
+		return nil, fmt.Errorf("file %s contains no times", path)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	first := times[0]
+	for i := range times {
+		times[i] -= first
+	}
+	return times, nil
+}
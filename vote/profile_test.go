@@ -0,0 +1,42 @@
+package vote
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalOffsets(t *testing.T) {
+	offsets := normalOffsets(1000, time.Minute, 0, 0)
+
+	inner := 0
+	for i, offset := range offsets {
+		if offset < 0 || offset > time.Minute {
+			t.Fatalf("offset %d is %v, expected between 0 and 1m", i, offset)
+		}
+
+		if i > 0 && offset < offsets[i-1] {
+			t.Fatalf("offsets are not sorted at %d", i)
+		}
+
+		// One standard deviation around the peak.
+		if offset >= 20*time.Second && offset <= 40*time.Second {
+			inner++
+		}
+	}
+
+	if inner < 670 || inner > 700 {
+		t.Errorf("got %d votes within one standard deviation, expected about 683", inner)
+	}
+}
+
+func TestReplayOffsets(t *testing.T) {
+	curve := []time.Duration{0, time.Second, 3 * time.Second}
+
+	got := replayOffsets(5, curve)
+	expect := []time.Duration{0, 500 * time.Millisecond, time.Second, 2 * time.Second, 3 * time.Second}
+	for i := range expect {
+		if got[i] != expect[i] {
+			t.Errorf("offset %d is %v, expected %v", i, got[i], expect[i])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package vote
 import (
 	"bufio"
 	"context"
+	"crypto/ecdh"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -29,9 +30,35 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 		return fmt.Errorf("login admin: %w", err)
 	}
 
-	meetingID, optionID, cryptKey, err := pollData(ctx, admin, o.PollID)
+	dist, err := parseDistribution(o.Distribution)
 	if err != nil {
-		return fmt.Errorf("getting poll data: %w", err)
+		return fmt.Errorf("parsing distribution: %w", err)
+	}
+
+	if len(o.PollIDs) > 0 && o.Rounds == 0 {
+		o.Rounds = len(o.PollIDs)
+	}
+
+	if o.Rounds > 1 && o.CreatePoll == "" && !o.ResetPoll && len(o.PollIDs) < o.Rounds {
+		return fmt.Errorf("each round needs a new poll, use --create-poll, --reset-poll or --poll-ids with %d ids", o.Rounds)
+	}
+
+	if o.Negative > 0 && !o.managed() {
+		return fmt.Errorf("--negative needs --reset-poll or --create-poll with a poll type other then analog")
+	}
+
+	startVotes, err := o.schedule()
+	if err != nil {
+		return fmt.Errorf("vote profile: %w", err)
+	}
+
+	meetingID := o.MeetingID
+	if o.CreatePoll == "" {
+		p, err := pollData(ctx, admin, o.pollID(1))
+		if err != nil {
+			return fmt.Errorf("getting poll data: %w", err)
+		}
+		meetingID = p.meetingID
 	}
 
 	clients := make([]*client.Client, o.Amount)
@@ -45,9 +72,32 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 
 	log.Printf("Login %d clients", len(clients))
 	start := time.Now()
-	MassLogin(ctx, clients, meetingID, o.BaseName, o.UsersPassword)
+	failed, err := MassLogin(ctx, clients, meetingID, o.BaseName, o.UsersPassword, o.Login)
+	if err != nil {
+		return fmt.Errorf("login clients: %w", err)
+	}
+
+	if len(failed) > 0 {
+		clients = failed.Remove(clients)
+		if len(clients) == 0 {
+			return fmt.Errorf("no client could log in")
+		}
+		log.Printf("%d logins failed, continue with %d clients: %s", len(failed), len(clients), failed)
+	}
 	log.Printf("All clients logged in %v", time.Now().Sub(start))
 
+	voters := selfVoters(clients)
+	if o.Delegation {
+		voters, err = delegatedVoters(ctx, admin, meetingID, clients)
+		if err != nil {
+			return fmt.Errorf("fetching vote delegations: %w", err)
+		}
+	}
+
+	if o.Rounds > 0 {
+		return o.rounds(ctx, cfg, admin, voters, dist, startVotes)
+	}
+
 	first := true
 
 	for first || o.Loop {
@@ -60,18 +110,216 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 			log.Println("Starting voting")
 		}
 
-		start := time.Now()
-		url := "/system/vote"
-		if err := massVotes(ctx, clients, url, o.PollID, optionID, cryptKey); err != nil {
-			return fmt.Errorf("mass vote: %w", err)
+		if _, err := o.round(ctx, cfg, admin, o.PollID, voters, dist, startVotes); err != nil {
+			return err
 		}
-		log.Printf("All Clients have voted in %v", time.Now().Sub(start))
 	}
 
 	return nil
 }
 
-func pollData(ctx context.Context, client *client.Client, pollID int) (meetingID, optionID int, cryptKey []byte, err error) {
+// rounds runs --rounds votes without user input.
+func (o Options) rounds(ctx context.Context, cfg client.Config, admin *client.Client, voters []voter, dist *distribution, start schedule) error {
+	var report strings.Builder
+	fmt.Fprintf(&report, "%6s %6s %12s\n", "Round", "Poll", "Duration")
+	defer func() {
+		log.Printf("Rounds:\n%s", report.String())
+	}()
+
+	for round := 1; round <= o.Rounds; round++ {
+		if round > 1 {
+			log.Printf("Wait %v before round %d", o.RoundPause, round)
+			timer := time.NewTimer(o.RoundPause)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			}
+		}
+
+		log.Printf("Starting round %d of %d", round, o.Rounds)
+		roundStart := time.Now()
+		pollID, err := o.round(ctx, cfg, admin, o.pollID(round), voters, dist, start)
+		d := time.Since(roundStart)
+
+		if err != nil {
+			cfg.Stats.AddError("vote round", "failed")
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("round %d: %w", round, err)
+		}
+
+		cfg.Stats.Add("vote round", d)
+		fmt.Fprintf(&report, "%6d %6d %12v\n", round, pollID, d.Round(time.Millisecond))
+		log.Printf("Round %d finished in %v", round, d)
+	}
+	return nil
+}
+
+// pollID returns the id of the poll for a round starting at 1.
+func (o Options) pollID(round int) int {
+	if len(o.PollIDs) == 0 {
+		return o.PollID
+	}
+	return o.PollIDs[(round-1)%len(o.PollIDs)]
+}
+
+// managed returns true, if the command starts and stops the polls.
+func (o Options) managed() bool {
+	return o.ResetPoll || (o.CreatePoll != "" && o.CreatePoll != "analog")
+}
+
+// round runs one vote on the poll and returns its id.
+//
+// With --create-poll, a new poll is created before the vote. With
+// --reset-poll, the poll is reset. In both cases, the poll is started before
+// the vote and finished afterwards.
+func (o Options) round(ctx context.Context, cfg client.Config, admin *client.Client, pollID int, voters []voter, dist *distribution, start schedule) (int, error) {
+	if o.CreatePoll != "" {
+		err := timed(cfg.Stats, "poll create", func() (err error) {
+			pollID, err = o.createPoll(ctx, admin, dist)
+			return err
+		})
+		if err != nil {
+			return pollID, fmt.Errorf("creating poll: %w", err)
+		}
+		log.Printf("Created poll %d", pollID)
+
+		if o.CreatePoll == "analog" {
+			_, err := logPollResult(ctx, admin, pollID)
+			return pollID, err
+		}
+	}
+
+	if o.ResetPoll && o.CreatePoll == "" {
+		if err := timed(cfg.Stats, "poll reset", func() error {
+			return setPollState(ctx, admin, "poll.reset", pollID)
+		}); err != nil {
+			return pollID, fmt.Errorf("resetting poll: %w", err)
+		}
+	}
+
+	if o.managed() {
+		if err := timed(cfg.Stats, "poll start", func() error {
+			return setPollState(ctx, admin, "poll.start", pollID)
+		}); err != nil {
+			return pollID, fmt.Errorf("starting poll: %w", err)
+		}
+	}
+
+	p, err := pollData(ctx, admin, pollID)
+	if err != nil {
+		return pollID, fmt.Errorf("getting poll data: %w", err)
+	}
+
+	if err := dist.validate(p); err != nil {
+		return pollID, fmt.Errorf("invalid distribution: %w", err)
+	}
+
+	var negative *negativeTest
+	negativeDone := make(chan error, 1)
+	if o.Negative > 0 {
+		negative = newNegativeTest(cfg, o.Negative)
+		go func() {
+			negativeDone <- negative.during(ctx, p, voters, admin.UserID())
+		}()
+	}
+
+	var checks *votedChecks
+	if o.VotedInterval > 0 {
+		checks = startVotedChecks(ctx, cfg.Stats, voters, p.id, o.VotedInterval)
+	}
+
+	var sent *tally
+	err = timed(cfg.Stats, "poll vote", func() (err error) {
+		sent, err = massVotes(ctx, voters, "/system/vote", p, dist, start)
+		return err
+	})
+
+	if checks != nil {
+		checks.stop()
+	}
+
+	if err != nil {
+		return pollID, fmt.Errorf("mass vote: %w", err)
+	}
+	log.Printf("Votes for poll %d:\n%s", p.id, sent)
+
+	if negative != nil {
+		if err := <-negativeDone; err != nil {
+			return pollID, fmt.Errorf("sending invalid votes: %w", err)
+		}
+
+		if err := negative.again(ctx, "duplicate", p, voters, sent, 400); err != nil {
+			return pollID, fmt.Errorf("sending duplicate votes: %w", err)
+		}
+	}
+
+	if !o.managed() {
+		return pollID, nil
+	}
+
+	if err := timed(cfg.Stats, "poll stop", func() error {
+		return setPollState(ctx, admin, "poll.stop", pollID)
+	}); err != nil {
+		return pollID, fmt.Errorf("stopping poll: %w", err)
+	}
+
+	if negative != nil {
+		if err := negative.again(ctx, "late", p, voters, sent, 400, 404); err != nil {
+			return pollID, fmt.Errorf("sending late votes: %w", err)
+		}
+
+		log.Printf("Rejected invalid votes: %s", negative)
+		if err := negative.err(); err != nil {
+			return pollID, err
+		}
+	}
+
+	result, err := logPollResult(ctx, admin, pollID)
+	if err != nil {
+		return pollID, err
+	}
+
+	// Without vote-decrypt, the server only knows the amount of encrypted
+	// votes.
+	onlyCount := p.typ == "cryptographic" && result.votesRaw == ""
+	if p.typ == "cryptographic" && !onlyCount {
+		if err := verifySignature(ctx, admin, result); err != nil {
+			return pollID, fmt.Errorf("verifying poll %d: %w", pollID, err)
+		}
+		log.Printf("Signature of the decrypted votes of poll %d is valid", pollID)
+	}
+
+	if err := result.verify(sent, onlyCount); err != nil {
+		return pollID, fmt.Errorf("verifying poll %d: %w", pollID, err)
+	}
+	log.Printf("Result of poll %d matches the sent votes", pollID)
+
+	if o.CreatePoll == "" {
+		return pollID, nil
+	}
+
+	if err := timed(cfg.Stats, "poll publish", func() error {
+		return setPollState(ctx, admin, "poll.publish", pollID)
+	}); err != nil {
+		return pollID, fmt.Errorf("publishing poll: %w", err)
+	}
+	return pollID, nil
+}
+
+func logPollResult(ctx context.Context, admin *client.Client, pollID int) (pollResult, error) {
+	result, err := fetchPollResult(ctx, admin, pollID)
+	if err != nil {
+		return pollResult{}, fmt.Errorf("fetching poll result: %w", err)
+	}
+	log.Printf("Result of poll %d:\n%s", pollID, result)
+	return result, nil
+}
+
+func pollData(ctx context.Context, client *client.Client, pollID int) (poll, error) {
 	requestBody := fmt.Sprintf(
 		`[{
 			"collection":"poll",
@@ -79,6 +327,8 @@ func pollData(ctx context.Context, client *client.Client, pollID int) (meetingID
 			"fields": {
 				"meeting_id": null,
 				"option_ids": null,
+				"type": null,
+				"pollmethod": null,
 				"crypt_key":null
 			}
 		}]`,
@@ -86,51 +336,64 @@ func pollData(ctx context.Context, client *client.Client, pollID int) (meetingID
 	)
 	req, err := http.NewRequestWithContext(ctx, "GET", "/system/autoupdate?single=1", strings.NewReader(requestBody))
 	if err != nil {
-		return 0, 0, nil, fmt.Errorf("building request: %w", err)
+		return poll{}, fmt.Errorf("building request: %w", err)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, 0, nil, fmt.Errorf("getting response: %w", err)
+		return poll{}, fmt.Errorf("getting response: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var data map[string]json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, 0, nil, fmt.Errorf("decoding response body: %w", err)
+		return poll{}, fmt.Errorf("decoding response body: %w", err)
 	}
 
 	rawMeetingID, ok := data[fmt.Sprintf("poll/%d/meeting_id", pollID)]
 	if !ok {
-		return 0, 0, nil, fmt.Errorf("meeting_id not in response, got %v", dataKeys(data))
+		return poll{}, fmt.Errorf("meeting_id not in response, got %v", dataKeys(data))
 	}
 
 	rawOptionIDs, ok := data[fmt.Sprintf("poll/%d/option_ids", pollID)]
 	if !ok {
-		return 0, 0, nil, fmt.Errorf("option_ids not in response, got %v", dataKeys(data))
+		return poll{}, fmt.Errorf("option_ids not in response, got %v", dataKeys(data))
+	}
+
+	p := poll{id: pollID}
+	if err := json.Unmarshal(rawMeetingID, &p.meetingID); err != nil {
+		return poll{}, fmt.Errorf("decoding meeting_id from %q: %w", rawMeetingID, err)
 	}
 
-	if err := json.Unmarshal(rawMeetingID, &meetingID); err != nil {
-		return 0, 0, nil, fmt.Errorf("decoding meeting_id from %q: %w", rawMeetingID, err)
+	if err := json.Unmarshal(rawOptionIDs, &p.optionIDs); err != nil {
+		return poll{}, fmt.Errorf("decoding option_ids from %q: %w", rawOptionIDs, err)
 	}
 
-	var optionIDs []int
-	if err := json.Unmarshal(rawOptionIDs, &optionIDs); err != nil {
-		return 0, 0, nil, fmt.Errorf("decoding meeting_id from %q: %w", rawMeetingID, err)
+	if len(p.optionIDs) == 0 {
+		return poll{}, fmt.Errorf("poll %d has no options", pollID)
 	}
 
-	if len(optionIDs) != 1 {
-		return 0, 0, nil, fmt.Errorf("option_ids is %q, expected one value", rawOptionIDs)
+	p.method = "Y"
+	if rawMethod, ok := data[fmt.Sprintf("poll/%d/pollmethod", pollID)]; ok {
+		if err := json.Unmarshal(rawMethod, &p.method); err != nil {
+			return poll{}, fmt.Errorf("decoding pollmethod from %q: %w", rawMethod, err)
+		}
+	}
+
+	if rawType, ok := data[fmt.Sprintf("poll/%d/type", pollID)]; ok {
+		if err := json.Unmarshal(rawType, &p.typ); err != nil {
+			return poll{}, fmt.Errorf("decoding type from %q: %w", rawType, err)
+		}
 	}
 
 	cryptKeyRaw := data[fmt.Sprintf("poll/%d/crypt_key", pollID)]
 	if cryptKeyRaw != nil {
-		if err := json.Unmarshal(cryptKeyRaw, &cryptKey); err != nil {
-			return 0, 0, nil, fmt.Errorf("decoding crypt key: %w", err)
+		if err := json.Unmarshal(cryptKeyRaw, &p.cryptKey); err != nil {
+			return poll{}, fmt.Errorf("decoding crypt key: %w", err)
 		}
 	}
 
-	return meetingID, optionIDs[0], cryptKey, nil
+	return p, nil
 }
 
 func dataKeys(m map[string]json.RawMessage) []string {
@@ -141,82 +404,104 @@ func dataKeys(m map[string]json.RawMessage) []string {
 	return keys
 }
 
-// MassLogin logs in a list of clients.
-func MassLogin(ctx context.Context, clients []*client.Client, meetingID int, basename string, password string) {
-	var wgLogin sync.WaitGroup
-	progress := mpb.New(mpb.WithWaitGroup(&wgLogin))
-	loginBar := progress.AddBar(int64(len(clients)))
+// massVotes sends one vote for each voter.
+//
+// The answers of the votes are chosen by the distribution. If start is set,
+// the votes are sent with this schedule. Otherwise all votes are sent at once.
+// It returns the votes, that were accepted.
+func massVotes(ctx context.Context, voters []voter, url string, p poll, dist *distribution, start schedule) (*tally, error) {
+	// The payloads are created before the first vote is sent, so the
+	// encryption does not influence the time of the votes.
+	ballots := make([]ballot, len(voters))
+	payloads := make([]string, len(voters))
+	for i, v := range voters {
+		b, err := dist.vote(p)
+		if err != nil {
+			return nil, fmt.Errorf("creating vote: %w", err)
+		}
 
-	for i := 0; i < len(clients); i++ {
-		wgLogin.Add(1)
-		go func(i int) {
-			defer wgLogin.Done()
+		payload, err := votePayload(p, v, b)
+		if err != nil {
+			return nil, err
+		}
 
-			client := clients[i]
+		ballots[i] = b
+		payloads[i] = payload
+	}
 
-			username := fmt.Sprintf("%s%d", basename, i+1)
-			if meetingID > 0 {
-				username = fmt.Sprintf("m%d%s", meetingID, username)
-			}
+	sent := newTally()
 
-			if err := client.LoginWithCredentials(ctx, username, password); err != nil {
-				log.Printf("Login failed for user %s: %v", username, err)
-				return
-			}
+	var wgVote sync.WaitGroup
+	progress := mpb.New(mpb.WithWaitGroup(&wgVote))
+	voteBar := progress.AddBar(int64(len(voters)))
 
-			loginBar.Increment()
-		}(i)
-	}
-	progress.Wait()
-}
+	vote := func(ctx context.Context, i int) {
+		defer voteBar.Increment()
 
-func massVotes(ctx context.Context, clients []*client.Client, url string, pollID, optionID int, cryptKey []byte) error {
-	voteValue := fmt.Sprintf(`{"%d": "Y"}`, optionID)
-	if cryptKey != nil {
-		encrypted, err := crypto.Encrypt(rand.Reader, nil, cryptKey, []byte(voteValue))
+		client := voters[i].client
+		req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s?id=%d", url, p.id), strings.NewReader(payloads[i]))
 		if err != nil {
-			return fmt.Errorf("encrypt vote: %w", err)
+			log.Printf("Error creating request: %v", err)
+			return
 		}
 
-		encoded, err := json.Marshal(encrypted)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
 		if err != nil {
-			return fmt.Errorf("encode vote: %w", err)
+			log.Printf("Error sending vote request to %s for user %d: %v", url, i+1, err)
+			return
 		}
+		defer resp.Body.Close()
+		io.ReadAll(resp.Body)
 
-		voteValue = string(encoded)
+		sent.accept(i, ballots[i])
 	}
 
-	payload := fmt.Sprintf(`{"value": %s}`, voteValue)
+	if start != nil {
+		wgVote.Add(1)
+		err := start(ctx, len(voters), vote)
+		wgVote.Done()
+		if !voteBar.Completed() {
+			// The context was canceled before all votes were sent.
+			voteBar.Abort(false)
+		}
+		progress.Wait()
+		return sent, err
+	}
 
-	var wgVote sync.WaitGroup
-	progress := mpb.New(mpb.WithWaitGroup(&wgVote))
-	voteBar := progress.AddBar(int64(len(clients)))
-	for i := 0; i < len(clients); i++ {
+	for i := 0; i < len(voters); i++ {
 		wgVote.Add(1)
 		go func(i int) {
 			defer wgVote.Done()
-			defer voteBar.Increment()
+			vote(ctx, i)
+		}(i)
+	}
+	progress.Wait()
+	return sent, nil
+}
 
-			client := clients[i]
-			req, err := http.NewRequest("POST", fmt.Sprintf("%s?id=%d", url, pollID), strings.NewReader(payload))
-			if err != nil {
-				log.Printf("Error creating request: %v", err)
-				return
-			}
+// votePayload returns the body of the vote request for the ballot.
+func votePayload(p poll, v voter, b ballot) (string, error) {
+	value, err := b.value(p.method)
+	if err != nil {
+		return "", fmt.Errorf("encode vote: %w", err)
+	}
 
-			req.Header.Set("Content-Type", "application/json")
+	if p.cryptKey != nil {
+		encrypted, err := crypto.Encrypt(rand.Reader, ecdh.X25519(), p.cryptKey, value)
+		if err != nil {
+			return "", fmt.Errorf("encrypt vote: %w", err)
+		}
 
-			resp, err := client.Do(req)
-			if err != nil {
-				log.Printf("Error sending vote request to %s for user %d: %v", url, i+1, err)
-				return
-			}
-			defer resp.Body.Close()
-			io.ReadAll(resp.Body)
+		value, err = json.Marshal(encrypted)
+		if err != nil {
+			return "", fmt.Errorf("encode vote: %w", err)
+		}
+	}
 
-			return
-		}(i)
+	if v.userID != 0 {
+		return fmt.Sprintf(`{"user_id": %d, "value": %s}`, v.userID, value), nil
 	}
-	progress.Wait()
-	return nil
+	return fmt.Sprintf(`{"value": %s}`, value), nil
 }
@@ -0,0 +1,120 @@
+package vote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenSlides/openslides-performance/client"
+	"github.com/OpenSlides/openslides-performance/stats"
+)
+
+// votedChecks lets each client ask the vote service periodically, if it has
+// already voted. This is what the browser of each delegate does.
+type votedChecks struct {
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	start    time.Time
+	requests atomic.Int64
+
+	collector *stats.Collector
+
+	// votesBefore and checksBefore are the histograms from before the checks
+	// were started. They are used to report only the values while the checks
+	// were running.
+	votesBefore  stats.Histogram
+	checksBefore stats.Histogram
+}
+
+// startVotedChecks starts the checks for the poll until stop is called.
+//
+// The first check of each client is at a random time in the first interval,
+// so the checks are spread.
+func startVotedChecks(ctx context.Context, collector *stats.Collector, voters []voter, pollID int, interval time.Duration) *votedChecks {
+	ctx, cancel := context.WithCancel(ctx)
+	v := &votedChecks{
+		cancel:       cancel,
+		start:        time.Now(),
+		collector:    collector,
+		votesBefore:  collector.Histogram("/system/vote"),
+		checksBefore: collector.Histogram("/system/vote/voted"),
+	}
+
+	seen := make(map[*client.Client]bool, len(voters))
+	for _, voter := range voters {
+		if seen[voter.client] {
+			// A delegate checks only once for all principals.
+			continue
+		}
+		seen[voter.client] = true
+
+		v.wg.Add(1)
+		go func(c *client.Client) {
+			defer v.wg.Done()
+			v.run(ctx, c, pollID, interval)
+		}(voter.client)
+	}
+	return v
+}
+
+func (v *votedChecks) run(ctx context.Context, c *client.Client, pollID int, interval time.Duration) {
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(interval))))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/system/vote/voted?ids=%d", pollID), nil)
+		if err != nil {
+			return
+		}
+
+		// Errors are recorded by the client.
+		if resp, err := c.Do(req); err == nil {
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		v.requests.Add(1)
+
+		timer.Reset(interval)
+	}
+}
+
+// stop stops the checks and logs, how they affected the votes.
+//
+// Only the votes and checks, that were sent while the checks were running, are
+// reported.
+func (v *votedChecks) stop() {
+	v.cancel()
+	v.wg.Wait()
+
+	d := time.Since(v.start)
+	requests := v.requests.Load()
+	if requests == 0 {
+		log.Printf("Warning: no voted check was sent in %v. Use a --voted-interval that is shorter then the vote.", d.Round(time.Millisecond))
+		return
+	}
+	log.Printf("Sent %d voted checks in %v (%.1f/s)", requests, d.Round(time.Millisecond), float64(requests)/d.Seconds())
+
+	if v.collector == nil {
+		return
+	}
+
+	votes := v.collector.Histogram("/system/vote").Since(v.votesBefore)
+	checks := v.collector.Histogram("/system/vote/voted").Since(v.checksBefore)
+	log.Printf(
+		"Vote latency with voted checks: p50 %v, p90 %v, max %v. Voted check latency: p50 %v, p90 %v, max %v",
+		votes.Percentile(50), votes.Percentile(90), votes.Max(),
+		checks.Percentile(50), checks.Percentile(90), checks.Max(),
+	)
+}
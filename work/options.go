@@ -1,11 +1,15 @@
 package work
 
+import "github.com/OpenSlides/openslides-performance/rate"
+
 // Options is the meta information for the cli.
 type Options struct {
 	Amount int `help:"Amount of action to be called." short:"n" default:"10"`
 
 	MeetingID int    `help:"Meeting id to use." short:"m" default:"1"`
 	Strategy  string `help:"Strategy for the background tasks." short:"s" default:"topic-done" enum:"topic-done,motion-state"`
+
+	Arrival rate.Options `embed:""`
 }
 
 // Help returns the help message
@@ -15,5 +19,15 @@ be set via the argument --strategy which is a string. Possible strategies are:
 
 * topic-done: sets the done status of a topic to true and false
 
-* motion-state: sets the state of a motion to 2 and then resets it.`
+* motion-state: sets the state of a motion to 2 and then resets it.
+
+Per default, each of the --amount workers sends the next action, when the
+last one was answered. With --rate, the actions are sent with a fixed
+arrival rate and are spread over all workers. The actions of one worker are
+still sent one after another, so an arrival waits, if the last action of its
+worker was not answered yet.
+
+Example:
+
+openslides-performance work -n 10 --rate 50/s`
 }
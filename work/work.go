@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -14,17 +15,32 @@ import (
 
 // Run runs the command.
 func (o Options) Run(ctx context.Context, cfg client.Config) error {
-	workFunc := topicDone
+	if o.Amount <= 0 {
+		return fmt.Errorf("--amount has to be at least 1")
+	}
+
+	newWorker := newTopicWorker
 	switch o.Strategy {
 	case "topic-done":
-		workFunc = topicDone
+		newWorker = newTopicWorker
 	case "motion-state":
-		workFunc = motionState
+		newWorker = newMotionWorker
 	}
 
+	if o.Arrival.Enabled() {
+		return o.runWithRate(ctx, cfg, newWorker)
+	}
+
+	ramp := cfg.Ramp(o.Amount)
 	eg, ctx := errgroup.WithContext(ctx)
 	for i := 0; i < o.Amount; i++ {
 		eg.Go(func() error {
+			ctx, cancel, err := ramp.Wait(ctx, i)
+			if err != nil {
+				return nil
+			}
+			defer cancel()
+
 			cli, err := client.New(cfg)
 			if err != nil {
 				return fmt.Errorf("creating client: %w", err)
@@ -34,38 +50,199 @@ func (o Options) Run(ctx context.Context, cfg client.Config) error {
 				return fmt.Errorf("login client: %w", err)
 			}
 
-			return workFunc(ctx, cli, o.MeetingID)
+			w, err := newWorker(ctx, cli, o.MeetingID)
+			if err != nil {
+				return fmt.Errorf("creating worker: %w", err)
+			}
+
+			return runWorker(ctx, w)
 		})
 	}
 
 	return eg.Wait()
 }
 
-func motionState(ctx context.Context, client *client.Client, meetingID int) (err error) {
-	motionID, err := createWorkerMotion(ctx, client, meetingID)
-	if err != nil {
-		return fmt.Errorf("creating motion: %w", err)
+// runWithRate creates the workers and sends the actions with the arrival
+// rate. Each arrival sends one action for the next worker.
+func (o Options) runWithRate(ctx context.Context, cfg client.Config, newWorker workerFactory) error {
+	workers := make([]worker, o.Amount)
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i := 0; i < o.Amount; i++ {
+		eg.Go(func() error {
+			cli, err := client.New(cfg)
+			if err != nil {
+				return fmt.Errorf("creating client: %w", err)
+			}
+
+			if err := cli.Login(egCtx); err != nil {
+				return fmt.Errorf("login client: %w", err)
+			}
+
+			w, err := newWorker(egCtx, cli, o.MeetingID)
+			if err != nil {
+				return fmt.Errorf("creating worker: %w", err)
+			}
+
+			workers[i] = w
+			return nil
+		})
 	}
 
-	nextStateID, err := motionNextStateID(ctx, client, motionID)
+	err := eg.Wait()
+
+	defer func() {
+		for _, w := range workers {
+			if w == nil {
+				continue
+			}
+
+			if err := w.cleanup(context.Background()); err != nil {
+				log.Printf("Cleanup worker: %v", err)
+			}
+		}
+	}()
+
 	if err != nil {
-		return fmt.Errorf("getting id of next state: %w", err)
+		return err
+	}
+
+	serial := make([]*serialWorker, len(workers))
+	for i, w := range workers {
+		serial[i] = newSerialWorker(w)
 	}
 
+	return o.Arrival.Run(ctx, 0, func(ctx context.Context, i int) {
+		if err := serial[i%len(serial)].action(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Action failed: %v", err)
+		}
+	})
+}
+
+// serialWorker sends the actions of a worker one after another.
+//
+// With an arrival rate, the next arrival for a worker can come before its last
+// action was answered. The actions have to wait, so the same change is not
+// sent twice. The next change is chosen from the last successful one.
+type serialWorker struct {
+	worker worker
+	lock   chan struct{}
+	n      int
+}
+
+func newSerialWorker(w worker) *serialWorker {
+	return &serialWorker{
+		worker: w,
+		lock:   make(chan struct{}, 1),
+	}
+}
+
+func (s *serialWorker) action(ctx context.Context) error {
+	select {
+	case s.lock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.lock }()
+
+	if err := s.worker.action(ctx, s.n); err != nil {
+		return err
+	}
+	s.n++
+	return nil
+}
+
+// worker creates load by changing one object again and again.
+type worker interface {
+	// action sends the n-th change of the object.
+	action(ctx context.Context, n int) error
+
+	// cleanup deletes the object.
+	cleanup(ctx context.Context) error
+}
+
+// workerFactory creates the object of a worker.
+type workerFactory func(ctx context.Context, client *client.Client, meetingID int) (worker, error)
+
+// runWorker sends the actions of the worker until the context is done.
+func runWorker(ctx context.Context, w worker) (err error) {
 	defer func() {
-		deleteErr := deleteWorkerMotion(context.Background(), client, motionID)
+		deleteErr := w.cleanup(context.Background())
 		if err == nil && deleteErr != nil {
-			err = fmt.Errorf("deleting motion: %w", err)
+			err = fmt.Errorf("cleanup: %w", deleteErr)
 		}
 	}()
 
-	if err := toggleWorkerMotion(ctx, client, motionID, nextStateID); err != nil {
-		return fmt.Errorf("toggle motion: %w", err)
+	for n := 0; ; n++ {
+		if err := w.action(ctx, n); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// motionWorker sets the state of a motion and resets it.
+type motionWorker struct {
+	client      *client.Client
+	motionID    int
+	nextStateID int
+}
+
+func newMotionWorker(ctx context.Context, client *client.Client, meetingID int) (worker, error) {
+	motionID, err := createWorkerMotion(ctx, client, meetingID)
+	if err != nil {
+		return nil, fmt.Errorf("creating motion: %w", err)
+	}
+
+	nextStateID, err := motionNextStateID(ctx, client, motionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting id of next state: %w", err)
+	}
+
+	return motionWorker{
+		client:      client,
+		motionID:    motionID,
+		nextStateID: nextStateID,
+	}, nil
+}
+
+func (w motionWorker) action(ctx context.Context, n int) error {
+	body := fmt.Sprintf(
+		`[{"action":"motion.set_state","data":[{"id":%d,"state_id":%d}]}]`,
+		w.motionID,
+		w.nextStateID,
+	)
+
+	if n%2 == 1 {
+		body = fmt.Sprintf(
+			`[{"action":"motion.reset_state","data":[{"id":%d}]}]`,
+			w.motionID,
+		)
+	}
+
+	var respBody struct {
+		Success bool `json:"success"`
+	}
+
+	if err := backendAction(ctx, w.client, body, &respBody); err != nil {
+		return fmt.Errorf("sending action to backend: %w", err)
+	}
+
+	if !respBody.Success {
+		return fmt.Errorf("backend returned no success")
 	}
 
 	return nil
 }
 
+func (w motionWorker) cleanup(ctx context.Context) error {
+	if err := deleteWorkerMotion(ctx, w.client, w.motionID); err != nil {
+		return fmt.Errorf("deleting motion: %w", err)
+	}
+	return nil
+}
+
 func createWorkerMotion(ctx context.Context, client *client.Client, meetingID int) (int, error) {
 	body := fmt.Sprintf(
 		`[{"action":"motion.create","data":[{"meeting_id":%d,"title":"worker-motion","text":"<p>dummy</p>"}]}]`,
@@ -144,44 +321,6 @@ func motionNextStateID(ctx context.Context, client *client.Client, motionID int)
 	return nextStateIDs[0], nil
 }
 
-func toggleWorkerMotion(ctx context.Context, client *client.Client, motionID int, nextStateID int) error {
-	bodyNextState := fmt.Sprintf(
-		`[{"action":"motion.set_state","data":[{"id":%d,"state_id":%d}]}]`,
-		motionID,
-		nextStateID,
-	)
-
-	bodyReset := fmt.Sprintf(
-		`[{"action":"motion.reset_state","data":[{"id":%d}]}]`,
-		motionID,
-	)
-
-	toggleState := false
-	for {
-		body := bodyNextState
-		if toggleState {
-			body = bodyReset
-		}
-
-		var respBody struct {
-			Success bool `json:"success"`
-		}
-
-		if err := backendAction(ctx, client, body, &respBody); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return nil
-			}
-			return fmt.Errorf("sending action to backend: %w", err)
-		}
-
-		if !respBody.Success {
-			return fmt.Errorf("backend returned no success")
-		}
-
-		toggleState = !toggleState
-	}
-}
-
 func deleteWorkerMotion(ctx context.Context, client *client.Client, motionID int) error {
 	body := fmt.Sprintf(
 		`[{"action":"motion.delete","data":[{"id":%d}]}]`,
@@ -203,31 +342,60 @@ func deleteWorkerMotion(ctx context.Context, client *client.Client, motionID int
 	return nil
 }
 
-func topicDone(ctx context.Context, client *client.Client, meetingID int) (err error) {
+// topicWorker opens and closes the agenda item of a topic.
+type topicWorker struct {
+	client       *client.Client
+	topicID      int
+	agendaItemID int
+}
+
+func newTopicWorker(ctx context.Context, client *client.Client, meetingID int) (worker, error) {
 	topicID, err := createWorkerTopic(ctx, client, meetingID)
 	if err != nil {
-		return fmt.Errorf("creating topic: %w", err)
+		return nil, fmt.Errorf("creating topic: %w", err)
 	}
 
 	aid, err := agendaID(ctx, client, topicID)
 	if err != nil {
-		return fmt.Errorf("fetching agenda id for topic %d: %w", topicID, err)
+		return nil, fmt.Errorf("fetching agenda id for topic %d: %w", topicID, err)
 	}
 
-	defer func() {
-		deleteErr := deleteWorkerTopic(context.Background(), client, topicID)
-		if err == nil && deleteErr != nil {
-			err = fmt.Errorf("deleting topic: %w", err)
-		}
-	}()
+	return topicWorker{
+		client:       client,
+		topicID:      topicID,
+		agendaItemID: aid,
+	}, nil
+}
 
-	if err := toggleWorkerAgendaItem(ctx, client, aid); err != nil {
-		return fmt.Errorf("toggle agenda item: %w", err)
+func (w topicWorker) action(ctx context.Context, n int) error {
+	body := fmt.Sprintf(
+		`[{"action":"agenda_item.update","data":[{"id":%d,"closed":%s}]}]`,
+		w.agendaItemID,
+		boolToStr(n%2 == 0),
+	)
+
+	var respBody struct {
+		Success bool `json:"success"`
+	}
+
+	if err := backendAction(ctx, w.client, body, &respBody); err != nil {
+		return fmt.Errorf("sending action to backend: %w", err)
+	}
+
+	if !respBody.Success {
+		return fmt.Errorf("backend returned no success")
 	}
 
 	return nil
 }
 
+func (w topicWorker) cleanup(ctx context.Context) error {
+	if err := deleteWorkerTopic(ctx, w.client, w.topicID); err != nil {
+		return fmt.Errorf("deleting topic: %w", err)
+	}
+	return nil
+}
+
 func createWorkerTopic(ctx context.Context, client *client.Client, meetingID int) (topicID int, err error) {
 	body := fmt.Sprintf(
 		`[{"action":"topic.create","data":[{"meeting_id":%d,"title":"woker-topic"}]}]`,
@@ -296,34 +464,6 @@ func boolToStr(b bool) string {
 	return "false"
 }
 
-func toggleWorkerAgendaItem(ctx context.Context, client *client.Client, topicID int) error {
-	toState := true
-	for {
-		body := fmt.Sprintf(
-			`[{"action":"agenda_item.update","data":[{"id":%d,"closed":%s}]}]`,
-			topicID,
-			boolToStr(toState),
-		)
-
-		var respBody struct {
-			Success bool `json:"success"`
-		}
-
-		if err := backendAction(ctx, client, body, &respBody); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return nil
-			}
-			return fmt.Errorf("sending action to backend: %w", err)
-		}
-
-		if !respBody.Success {
-			return fmt.Errorf("backend returned no success")
-		}
-
-		toState = !toState
-	}
-}
-
 func deleteWorkerTopic(ctx context.Context, client *client.Client, topicID int) error {
 	body := fmt.Sprintf(
 		`[{"action":"topic.delete","data":[{"id":%d}]}]`,